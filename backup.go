@@ -0,0 +1,341 @@
+package vuforia
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrImageNotFound is returned by an ImageSource that does not have the image of a target
+var ErrImageNotFound = errors.New("vuforia target image not found")
+
+// ImageSource provides the images of the targets, which VWS does not return
+type ImageSource interface {
+	// Image returns the image of the target, or ErrImageNotFound
+	Image(ctx context.Context, target TargetRecord) ([]byte, error)
+}
+
+// ImageSourceFunc adapts a function to an ImageSource
+type ImageSourceFunc func(ctx context.Context, target TargetRecord) ([]byte, error)
+
+func (f ImageSourceFunc) Image(ctx context.Context, target TargetRecord) ([]byte, error) {
+	return f(ctx, target)
+}
+
+// DirImageSource is an ImageSource reading the images from a directory, in a file named after
+// the target ID or the target name with a .jpg, .jpeg or .png extension.
+// Names that could refer to a file outside the directory are ignored.
+type DirImageSource string
+
+func (d DirImageSource) Image(ctx context.Context, target TargetRecord) ([]byte, error) {
+	for _, name := range []string{target.TargetId, target.Name} {
+		if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			continue
+		}
+		for _, ext := range []string{".jpg", ".jpeg", ".png"} {
+			data, err := ioutil.ReadFile(filepath.Join(string(d), name+ext))
+			if os.IsNotExist(err) {
+				continue
+			}
+			return data, err
+		}
+	}
+	return nil, ErrImageNotFound
+}
+
+// manifestVersion is the version of the format of the export archives
+const manifestVersion = 1
+
+// manifestName is the name of the manifest in the export archives
+const manifestName = "manifest.json"
+
+// ExportManifest describes the content of an export archive
+type ExportManifest struct {
+	Version    int              `json:"version"`
+	Database   string           `json:"database"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Targets    []ExportedTarget `json:"targets"`
+}
+
+// ExportedTarget is a target as exported
+type ExportedTarget struct {
+	Status  TargetStatus           `json:"status"`
+	Record  TargetRecord           `json:"target_record"`
+	Summary *TargetSummaryResponse `json:"summary"`
+	// Metadata is the base64 encoded application metadata, if known from the index
+	Metadata *string `json:"application_metadata,omitempty"`
+	// Tags are the tags of the target in the index
+	Tags []string `json:"tags,omitempty"`
+	// Image is the path of the image in the archive; empty if the image was not available
+	Image string `json:"image,omitempty"`
+}
+
+type ExportConfig struct {
+	// Client is used to retrieve the targets
+	Client Client
+	// TargetIds are the targets to export; all the targets of the database when empty (Optional)
+	TargetIds []string
+	// Index provides the metadata and tags of the targets (Optional)
+	Index *Index
+	// Images provides the images of the targets; targets are exported without image when not set (Optional)
+	Images ImageSource
+}
+
+// ExportDatabase writes a gzipped tar archive of the records, summaries, metadata and images of the targets.
+// The archive contains a manifest.json file describing the targets, see ExportManifest, and their images.
+func ExportDatabase(ctx context.Context, w io.Writer, cfg ExportConfig) (*ExportManifest, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("export Client must be set")
+	}
+
+	ids := cfg.TargetIds
+	if len(ids) == 0 {
+		list, err := listTargets(ctx, cfg.Client)
+		if err != nil {
+			return nil, err
+		}
+		ids = list.Results
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := &ExportManifest{Version: manifestVersion, ExportedAt: time.Now().UTC(), Targets: []ExportedTarget{}}
+	for _, id := range ids {
+		t, err := exportTarget(ctx, cfg, id)
+		var ae APIError
+		if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		manifest.Database = t.Summary.DatabaseName
+
+		if cfg.Images != nil {
+			image, err := cfg.Images.Image(ctx, t.Record)
+			if err != nil && !errors.Is(err, ErrImageNotFound) {
+				return nil, fmt.Errorf("reading image of target %s: %w", id, err)
+			}
+			if err == nil {
+				t.Image = "images/" + id
+				if err := writeTarFile(tw, t.Image, image); err != nil {
+					return nil, err
+				}
+			}
+		}
+		manifest.Targets = append(manifest.Targets, *t)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, manifestName, data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+func exportTarget(ctx context.Context, cfg ExportConfig, id string) (*ExportedTarget, error) {
+	output, err := cfg.Client.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := cfg.Client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: id})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ExportedTarget{Status: output.Status, Record: output.TargetRecord, Summary: summary}
+	if cfg.Index != nil {
+		if r, ok := cfg.Index.Get(id); ok {
+			t.Metadata, t.Tags = r.Metadata, r.Tags
+		}
+	}
+	return t, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+type RestoreConfig struct {
+	// Client is used to recreate the targets, in the same or another database
+	Client Client
+	// TargetIds are the targets of the archive to restore, by their exported ID; all the targets when empty (Optional)
+	TargetIds []string
+	// Index is updated with the metadata and tags of the restored targets (Optional)
+	Index *Index
+}
+
+// RestoreReport lists the outcome of the restoration of every target of the archive
+type RestoreReport struct {
+	// Restored maps the exported IDs of the recreated targets to their new IDs
+	Restored map[string]string
+	// Existing maps the exported IDs of the targets that exist in the database, by name, to their IDs
+	Existing map[string]string
+	// NoImage are the exported IDs of the missing targets that were exported without image
+	NoImage []string
+	// Failed are the exported IDs of the targets that could not be recreated
+	Failed map[string]error
+}
+
+// RestoreDatabase recreates the targets of an archive written by ExportDatabase that are missing from the
+// database, matching them by name, with their exported name, width, active flag, metadata and image.
+// The archive is read in memory.
+func RestoreDatabase(ctx context.Context, r io.Reader, cfg RestoreConfig) (*RestoreReport, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("restore Client must be set")
+	}
+
+	manifest, images, err := readExport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := targetsByName(ctx, cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	for _, id := range cfg.TargetIds {
+		selected[id] = true
+	}
+
+	report := &RestoreReport{Restored: map[string]string{}, Existing: map[string]string{}, Failed: map[string]error{}}
+	for _, t := range manifest.Targets {
+		id := t.Record.TargetId
+		if len(selected) > 0 && !selected[id] {
+			continue
+		}
+
+		if existingId, ok := existing[t.Record.Name]; ok {
+			report.Existing[id] = existingId
+			continue
+		}
+
+		image, ok := images[t.Image]
+		if t.Image == "" || !ok {
+			report.NoImage = append(report.NoImage, id)
+			continue
+		}
+
+		active := t.Record.Active
+		resp, err := cfg.Client.PostTarget(ctx, &PostTargetRequest{
+			Name:     t.Record.Name,
+			Width:    t.Record.Width,
+			Image:    base64.StdEncoding.EncodeToString(image),
+			Active:   &active,
+			Metadata: t.Metadata,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			report.Failed[id] = err
+			continue
+		}
+		report.Restored[id] = resp.TargetId
+
+		if cfg.Index != nil {
+			if err := cfg.Index.Update(resp.TargetId, func(r *IndexRecord) {
+				r.Name, r.Width, r.Active = t.Record.Name, t.Record.Width, active
+				r.Metadata, r.Tags = t.Metadata, t.Tags
+			}); err != nil {
+				report.Failed[id] = IndexError{Err: err}
+			}
+		}
+	}
+	return report, nil
+}
+
+// readExport reads the manifest and the images, by path, of an export archive
+func readExport(r io.Reader) (*ExportManifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gz.Close()
+
+	var manifest *ExportManifest
+	images := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if h.Name != manifestName {
+			images[h.Name] = data
+			continue
+		}
+
+		manifest = &ExportManifest{}
+		if err := json.Unmarshal(data, manifest); err != nil {
+			return nil, nil, err
+		}
+		if manifest.Version != manifestVersion {
+			return nil, nil, fmt.Errorf("unsupported export manifest version %d", manifest.Version)
+		}
+	}
+
+	if manifest == nil {
+		return nil, nil, errors.New("export archive has no " + manifestName)
+	}
+	return manifest, images, nil
+}
+
+// targetsByName returns the IDs of the targets of the database by name
+func targetsByName(ctx context.Context, client Client) (map[string]string, error) {
+	list, err := listTargets(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]string{}
+	for _, id := range list.Results {
+		output, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+		if err != nil {
+			var ae APIError
+			if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+				continue
+			}
+			return nil, err
+		}
+		ids[output.TargetRecord.Name] = id
+	}
+	return ids, nil
+}
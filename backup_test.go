@@ -0,0 +1,99 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestExportRestore(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	index, err := vuforia.OpenIndex(filepath.Join(dir, "index.json"))
+	require.NoError(t, err)
+	indexed := vuforia.NewIndexingClient(client, index)
+
+	metadata := base64.StdEncoding.EncodeToString([]byte("metadata"))
+	inactive := false
+	poster, err := indexed.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "poster", Width: 2, Image: base64.StdEncoding.EncodeToString([]byte("poster image")), Active: &inactive, Metadata: &metadata})
+	require.NoError(t, err)
+	require.NoError(t, index.SetTags(poster.TargetId, "summer"))
+	flyer := f.addTarget("flyer", 0, "success")
+	noImage := f.addTarget("no-image", 0, "success")
+
+	images := filepath.Join(dir, "images")
+	require.NoError(t, os.Mkdir(images, 0o700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(images, poster.TargetId+".jpg"), []byte("poster image"), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(images, "flyer.png"), []byte("flyer image"), 0o600))
+
+	// Names are not trusted to refer to files of the directory
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secret.png"), []byte("secret"), 0o600))
+	_, err = vuforia.DirImageSource(images).Image(ctx, vuforia.TargetRecord{TargetId: "unknown", Name: "../secret"})
+	require.ErrorIs(t, err, vuforia.ErrImageNotFound)
+
+	var archive bytes.Buffer
+	manifest, err := vuforia.ExportDatabase(ctx, &archive, vuforia.ExportConfig{Client: client, Index: index, Images: vuforia.DirImageSource(images)})
+	require.NoError(t, err)
+	require.Equal(t, "fake", manifest.Database)
+	require.Len(t, manifest.Targets, 3)
+
+	exported := map[string]vuforia.ExportedTarget{}
+	for _, t := range manifest.Targets {
+		exported[t.Record.TargetId] = t
+	}
+	require.Equal(t, &metadata, exported[poster.TargetId].Metadata)
+	require.Equal(t, []string{"summer"}, exported[poster.TargetId].Tags)
+	require.Equal(t, "images/"+flyer, exported[flyer].Image)
+	require.Empty(t, exported[noImage].Image)
+
+	t.Run("SameDatabase", func(t *testing.T) {
+		f.remove(poster.TargetId)
+		f.remove(noImage)
+
+		report, err := vuforia.RestoreDatabase(ctx, bytes.NewReader(archive.Bytes()), vuforia.RestoreConfig{Client: client})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{flyer: flyer}, report.Existing)
+		require.Equal(t, []string{noImage}, report.NoImage)
+		require.Empty(t, report.Failed)
+		require.Len(t, report.Restored, 1)
+
+		restored := f.target(report.Restored[poster.TargetId])
+		require.Equal(t, "poster", restored.name)
+		require.Equal(t, 2.0, restored.width)
+		require.False(t, restored.active)
+		require.Equal(t, metadata, restored.metadata)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte("poster image")), restored.image)
+	})
+
+	t.Run("OtherDatabase", func(t *testing.T) {
+		other := newFakeVWS(t)
+		otherIndex, err := vuforia.OpenIndex(filepath.Join(t.TempDir(), "index.json"))
+		require.NoError(t, err)
+
+		report, err := vuforia.RestoreDatabase(ctx, bytes.NewReader(archive.Bytes()), vuforia.RestoreConfig{
+			Client:    other.client(t, vuforia.ClientConfig{}),
+			TargetIds: []string{flyer},
+			Index:     otherIndex,
+		})
+		require.NoError(t, err)
+		require.Len(t, report.Restored, 1)
+		require.Equal(t, "flyer", other.target(report.Restored[flyer]).name)
+
+		r, ok := otherIndex.Get(report.Restored[flyer])
+		require.True(t, ok)
+		require.Equal(t, "flyer", r.Name)
+	})
+
+	_, err = vuforia.RestoreDatabase(ctx, bytes.NewReader([]byte("not an archive")), vuforia.RestoreConfig{Client: client})
+	require.Error(t, err)
+}
@@ -0,0 +1,336 @@
+package vuforia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type BulkAction string
+
+const (
+	BulkActivate   BulkAction = "activate"
+	BulkDeactivate BulkAction = "deactivate"
+	BulkDelete     BulkAction = "delete"
+	// BulkRescale sets the width of the targets
+	BulkRescale BulkAction = "rescale"
+)
+
+// TargetSelector selects the targets matching all of its criteria
+type TargetSelector struct {
+	// TargetIds are the candidate targets; all the targets of the database when empty (Optional)
+	TargetIds []string
+	// NameGlob selects the targets whose name matches the pattern, see path.Match (Optional)
+	NameGlob string
+	// NameRegexp selects the targets whose name matches the regular expression (Optional)
+	NameRegexp *regexp.Regexp
+	// Statuses selects the targets in one of the statuses (Optional)
+	Statuses []TargetStatus
+	// MinTrackingRating selects the targets rated at least the rating (Optional)
+	MinTrackingRating *TrackingRating
+	// MaxTrackingRating selects the targets rated at most the rating; unrated targets are not selected (Optional)
+	MaxTrackingRating *TrackingRating
+	// UploadedAfter selects the targets uploaded on or after the date (Optional)
+	UploadedAfter time.Time
+	// UploadedBefore selects the targets uploaded before the date (Optional)
+	UploadedBefore time.Time
+	// Tags selects the targets having all the tags in the Index (Optional)
+	Tags []string
+	// Index holds the tags of the targets; it must be set when Tags is
+	Index *Index
+}
+
+// validate reports the criteria that cannot be evaluated
+func (s TargetSelector) validate() error {
+	if _, err := path.Match(s.NameGlob, ""); err != nil {
+		return fmt.Errorf("invalid selector NameGlob %q: %w", s.NameGlob, err)
+	}
+
+	if len(s.Tags) > 0 && s.Index == nil {
+		return errors.New("selector Index must be set to select by tags")
+	}
+	return nil
+}
+
+// candidates returns the IDs of the targets to retrieve the summary of
+func (s TargetSelector) candidates(ctx context.Context, client Client) ([]string, error) {
+	ids := s.TargetIds
+	if len(ids) == 0 {
+		list, err := listTargets(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		ids = list.Results
+	}
+
+	if len(s.Tags) == 0 {
+		return ids, nil
+	}
+
+	var tagged []string
+	for _, id := range ids {
+		r, ok := s.Index.Get(id)
+		if !ok {
+			continue
+		}
+		all := true
+		for _, tag := range s.Tags {
+			all = all && r.HasTag(tag)
+		}
+		if all {
+			tagged = append(tagged, id)
+		}
+	}
+	return tagged, nil
+}
+
+func (s TargetSelector) matches(summary *TargetSummaryResponse) bool {
+	if s.NameGlob != "" {
+		if ok, _ := path.Match(s.NameGlob, summary.TargetName); !ok {
+			return false
+		}
+	}
+
+	if s.NameRegexp != nil && !s.NameRegexp.MatchString(summary.TargetName) {
+		return false
+	}
+
+	if len(s.Statuses) > 0 {
+		found := false
+		for _, status := range s.Statuses {
+			found = found || status == summary.Status
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if s.MinTrackingRating != nil && summary.TrackingRating < *s.MinTrackingRating {
+		return false
+	}
+
+	if s.MaxTrackingRating != nil && (!summary.TrackingRating.Rated() || summary.TrackingRating > *s.MaxTrackingRating) {
+		return false
+	}
+
+	if !s.UploadedAfter.IsZero() && summary.UploadDate.Before(s.UploadedAfter) {
+		return false
+	}
+
+	if !s.UploadedBefore.IsZero() && !summary.UploadDate.Before(s.UploadedBefore) {
+		return false
+	}
+
+	return true
+}
+
+type BulkConfig struct {
+	// Client is used to select and change the targets
+	Client Client
+	// Selector selects the targets to change
+	Selector TargetSelector
+	// Action is applied to every selected target
+	Action BulkAction
+	// Width is the new width of the targets for BulkRescale
+	Width float64
+	// Concurrency is the number of requests sent at once (Optional)
+	// Defaults to 4.
+	Concurrency int
+}
+
+// BulkTarget is a target selected by a BulkPlan
+type BulkTarget struct {
+	TargetId       string
+	Name           string
+	Status         TargetStatus
+	Active         bool
+	TrackingRating TrackingRating
+	UploadDate     Date
+}
+
+// BulkPlan is the preview of a bulk operation; nothing is changed until it is applied
+type BulkPlan struct {
+	cfg BulkConfig
+	// Targets are the selected targets, in the order of the candidates
+	Targets []BulkTarget
+	// Skipped are the selected targets already in the requested state
+	Skipped []BulkTarget
+}
+
+// BulkResult is the outcome of the action on a target
+type BulkResult struct {
+	TargetId string
+	Name     string
+	// Err is the error of the request; nil if the target was changed
+	Err error
+}
+
+// BulkReport is the outcome of an applied BulkPlan
+type BulkReport struct {
+	Action BulkAction
+	// Results are in the order of the plan targets
+	Results []BulkResult
+}
+
+// Failed returns the results of the targets that could not be changed
+func (r *BulkReport) Failed() []BulkResult {
+	var failed []BulkResult
+	for _, res := range r.Results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// PlanBulk selects the targets matching the selector, retrieving their summaries, without changing them
+func PlanBulk(ctx context.Context, cfg BulkConfig) (*BulkPlan, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("bulk Client must be set")
+	}
+
+	switch cfg.Action {
+	case BulkActivate, BulkDeactivate, BulkDelete:
+	case BulkRescale:
+		if cfg.Width <= 0 {
+			return nil, errors.New("bulk Width must be positive to rescale")
+		}
+	default:
+		return nil, fmt.Errorf("unknown bulk action %q", cfg.Action)
+	}
+
+	if err := cfg.Selector.validate(); err != nil {
+		return nil, fmt.Errorf("bulk %w", err)
+	}
+
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+
+	if cfg.Concurrency < 0 {
+		return nil, errors.New("bulk Concurrency must not be negative")
+	}
+
+	ids, err := cfg.Selector.candidates(ctx, cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*TargetSummaryResponse, len(ids))
+	errs := make([]error, len(ids))
+	forEach(ctx, len(ids), cfg.Concurrency, func(i int) {
+		summaries[i], errs[i] = cfg.Client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: ids[i]})
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	plan := &BulkPlan{cfg: cfg}
+	for i, s := range summaries {
+		if err := errs[i]; err != nil {
+			var ae APIError
+			if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+				continue
+			}
+			return nil, err
+		}
+		if !cfg.Selector.matches(s) {
+			continue
+		}
+
+		t := BulkTarget{
+			TargetId:       ids[i],
+			Name:           s.TargetName,
+			Status:         s.Status,
+			Active:         s.Active,
+			TrackingRating: s.TrackingRating,
+			UploadDate:     s.UploadDate,
+		}
+		if (cfg.Action == BulkActivate && t.Active) || (cfg.Action == BulkDeactivate && !t.Active) {
+			plan.Skipped = append(plan.Skipped, t)
+			continue
+		}
+		plan.Targets = append(plan.Targets, t)
+	}
+	return plan, nil
+}
+
+// WritePreview writes the action and the selected targets as a table
+func (p *BulkPlan) WritePreview(w io.Writer) error {
+	action := string(p.cfg.Action)
+	if p.cfg.Action == BulkRescale {
+		action = fmt.Sprintf("%s to width %g", action, p.cfg.Width)
+	}
+	if _, err := fmt.Fprintf(w, "%s %d targets, %d skipped\n", action, len(p.Targets), len(p.Skipped)); err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TARGET ID\tNAME\tSTATUS\tACTIVE\tRATING\tUPLOADED")
+	for _, t := range p.Targets {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%d\t%s\n", t.TargetId, t.Name, t.Status, t.Active, t.TrackingRating, t.UploadDate)
+	}
+	return tw.Flush()
+}
+
+// Apply performs the action on the targets of the plan, concurrently.
+// Failures are reported per target; the error is only set when the context is done.
+func (p *BulkPlan) Apply(ctx context.Context) (*BulkReport, error) {
+	report := &BulkReport{Action: p.cfg.Action, Results: make([]BulkResult, len(p.Targets))}
+	forEach(ctx, len(p.Targets), p.cfg.Concurrency, func(i int) {
+		t := p.Targets[i]
+		report.Results[i] = BulkResult{TargetId: t.TargetId, Name: t.Name, Err: p.apply(ctx, t.TargetId)}
+	})
+
+	for i := range report.Results {
+		if report.Results[i].TargetId == "" {
+			// Not attempted before the context was done
+			t := p.Targets[i]
+			report.Results[i] = BulkResult{TargetId: t.TargetId, Name: t.Name, Err: ctx.Err()}
+		}
+	}
+	return report, ctx.Err()
+}
+
+func (p *BulkPlan) apply(ctx context.Context, targetId string) error {
+	var err error
+	switch p.cfg.Action {
+	case BulkDelete:
+		_, err = p.cfg.Client.DeleteTarget(ctx, &DeleteTargetRequest{TargetId: targetId})
+	case BulkRescale:
+		width := p.cfg.Width
+		_, err = p.cfg.Client.UpdateTarget(ctx, &UpdateTargetRequest{TargetId: targetId, Width: &width})
+	default:
+		active := p.cfg.Action == BulkActivate
+		_, err = p.cfg.Client.UpdateTarget(ctx, &UpdateTargetRequest{TargetId: targetId, Active: &active})
+	}
+	return err
+}
+
+// forEach calls fn with every index from 0 to n-1, at most concurrency at once, until the context is done
+func forEach(ctx context.Context, n, concurrency int, fn func(i int)) {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,128 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestBulk(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	ctx := context.Background()
+
+	summer1 := f.addTarget("summer-1", 0, "success")
+	summer2 := f.addTarget("summer-2", 0, "success")
+	winter := f.addTarget("winter-1", 0, "success")
+	failed := f.addTarget("summer-3", 0, "failed")
+	f.update(summer2, func(t *fakeTarget) { t.rating = 2 })
+
+	_, err := vuforia.PlanBulk(ctx, vuforia.BulkConfig{Client: client, Action: "archive"})
+	require.Error(t, err)
+	_, err = vuforia.PlanBulk(ctx, vuforia.BulkConfig{Client: client, Action: vuforia.BulkRescale})
+	require.Error(t, err)
+	_, err = vuforia.PlanBulk(ctx, vuforia.BulkConfig{Client: client, Action: vuforia.BulkDelete, Selector: vuforia.TargetSelector{NameGlob: "[abc"}})
+	require.ErrorIs(t, err, path.ErrBadPattern)
+	_, err = vuforia.PlanBulk(ctx, vuforia.BulkConfig{Client: client, Action: vuforia.BulkDelete, Concurrency: -1})
+	require.Error(t, err)
+
+	t.Run("Deactivate", func(t *testing.T) {
+		plan, err := vuforia.PlanBulk(ctx, vuforia.BulkConfig{
+			Client:   client,
+			Action:   vuforia.BulkDeactivate,
+			Selector: vuforia.TargetSelector{NameGlob: "summer-*", Statuses: []vuforia.TargetStatus{vuforia.StatusSuccess}},
+		})
+		require.NoError(t, err)
+		require.Len(t, plan.Targets, 2)
+		require.ElementsMatch(t, []string{summer1, summer2}, []string{plan.Targets[0].TargetId, plan.Targets[1].TargetId})
+
+		var preview bytes.Buffer
+		require.NoError(t, plan.WritePreview(&preview))
+		require.Contains(t, preview.String(), "deactivate 2 targets, 0 skipped\n")
+		require.Contains(t, preview.String(), "summer-2")
+		require.True(t, f.target(summer1).active, "planning changes nothing")
+
+		report, err := plan.Apply(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Results, 2)
+		require.Empty(t, report.Failed())
+		require.False(t, f.target(summer1).active)
+		require.False(t, f.target(summer2).active)
+		require.True(t, f.target(winter).active)
+
+		plan, err = vuforia.PlanBulk(ctx, vuforia.BulkConfig{
+			Client:   client,
+			Action:   vuforia.BulkDeactivate,
+			Selector: vuforia.TargetSelector{NameRegexp: regexp.MustCompile(`^summer-[12]$`)},
+		})
+		require.NoError(t, err)
+		require.Empty(t, plan.Targets)
+		require.Len(t, plan.Skipped, 2)
+	})
+
+	t.Run("Rescale", func(t *testing.T) {
+		low := vuforia.TrackingRating(3)
+		plan, err := vuforia.PlanBulk(ctx, vuforia.BulkConfig{
+			Client:   client,
+			Action:   vuforia.BulkRescale,
+			Width:    2.5,
+			Selector: vuforia.TargetSelector{MaxTrackingRating: &low, Statuses: []vuforia.TargetStatus{vuforia.StatusSuccess}},
+		})
+		require.NoError(t, err)
+		require.Len(t, plan.Targets, 1)
+
+		unrated := f.addTarget("summer-4", 5, "success")
+		unratedPlan, err := vuforia.PlanBulk(ctx, vuforia.BulkConfig{
+			Client:   client,
+			Action:   vuforia.BulkRescale,
+			Width:    2.5,
+			Selector: vuforia.TargetSelector{TargetIds: []string{unrated}, MaxTrackingRating: &low},
+		})
+		require.NoError(t, err)
+		require.Empty(t, unratedPlan.Targets, "unrated targets are not rated at most 3")
+		f.remove(unrated)
+
+		report, err := plan.Apply(ctx)
+		require.NoError(t, err)
+		require.Empty(t, report.Failed())
+		require.Equal(t, 2.5, f.target(summer2).width)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		index, err := vuforia.OpenIndex(filepath.Join(t.TempDir(), "index.json"))
+		require.NoError(t, err)
+		for _, id := range []string{winter, failed} {
+			require.NoError(t, index.Put(vuforia.IndexRecord{TargetId: id, Tags: []string{"cleanup"}}))
+		}
+
+		_, err = vuforia.PlanBulk(ctx, vuforia.BulkConfig{Client: client, Action: vuforia.BulkDelete, Selector: vuforia.TargetSelector{Tags: []string{"cleanup"}}})
+		require.Error(t, err, "Index is required to select by tags")
+
+		plan, err := vuforia.PlanBulk(ctx, vuforia.BulkConfig{
+			Client: client,
+			Action: vuforia.BulkDelete,
+			Selector: vuforia.TargetSelector{
+				Tags:           []string{"cleanup"},
+				Index:          index,
+				UploadedBefore: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, plan.Targets, 2)
+
+		f.fail(500, "Fail")
+		report, err := plan.Apply(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Failed(), 1)
+		list, err := client.(vuforia.Lister).ListTargets(ctx)
+		require.NoError(t, err)
+		require.Len(t, list.Results, 3)
+	})
+}
@@ -0,0 +1,300 @@
+package vuforia
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type CacheConfig struct {
+	// GetTargetTTL is how long the responses of GetTarget are cached; a negative TTL disables the cache (Optional)
+	// Defaults to 1 minute.
+	GetTargetTTL time.Duration
+	// TargetSummaryTTL is how long the responses of TargetSummary are cached; a negative TTL disables the cache (Optional)
+	// Defaults to 5 minutes.
+	TargetSummaryTTL time.Duration
+	// DatabaseSummaryTTL is how long the responses of DatabaseSummary are cached; a negative TTL disables the cache (Optional)
+	// Defaults to 5 minutes.
+	DatabaseSummaryTTL time.Duration
+	// Clock tells the time the entries expire (Optional)
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// CacheStats counts the reads of a CachingClient
+type CacheStats struct {
+	// Hits are the reads served from the cache
+	Hits uint64
+	// Misses are the reads forwarded to the next client
+	Misses uint64
+	// Shared are the reads that waited for an identical read in flight instead of being forwarded
+	Shared uint64
+}
+
+// CachingClient is a Client caching the responses of GetTarget, TargetSummary and DatabaseSummary.
+// The entries of a target are invalidated when it is updated or deleted through the client, and the database
+// summary whenever a target is written; changes made through other clients are seen once the entries expire.
+// Concurrent identical reads are collapsed into a single request. Expired entries are removed as new ones
+// are cached, at most once per the shortest TTL.
+type CachingClient struct {
+	Client
+	cfg CacheConfig
+	// sweepInterval is the shortest TTL
+	sweepInterval time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*cacheCall
+	// generations are incremented on invalidation so that reads in flight are not cached; they are kept
+	// only while a read of the key is in flight
+	generations map[string]uint64
+	nextSweep   time.Time
+	stats       CacheStats
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+const cacheDatabaseKey = "database"
+
+// errCacheFetchPanicked is returned to the reads waiting for a read that panicked
+var errCacheFetchPanicked = errors.New("vuforia cached read panicked")
+
+func NewCachingClient(next Client, cfg CacheConfig) *CachingClient {
+	if cfg.GetTargetTTL == 0 {
+		cfg.GetTargetTTL = time.Minute
+	}
+
+	if cfg.TargetSummaryTTL == 0 {
+		cfg.TargetSummaryTTL = 5 * time.Minute
+	}
+
+	if cfg.DatabaseSummaryTTL == 0 {
+		cfg.DatabaseSummaryTTL = 5 * time.Minute
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+
+	var sweepInterval time.Duration
+	for _, ttl := range []time.Duration{cfg.GetTargetTTL, cfg.TargetSummaryTTL, cfg.DatabaseSummaryTTL} {
+		if ttl > 0 && (sweepInterval == 0 || ttl < sweepInterval) {
+			sweepInterval = ttl
+		}
+	}
+
+	return &CachingClient{
+		Client:        next,
+		cfg:           cfg,
+		sweepInterval: sweepInterval,
+		entries:       map[string]cacheEntry{},
+		inflight:      map[string]*cacheCall{},
+		generations:   map[string]uint64{},
+	}
+}
+
+func (c *CachingClient) GetTarget(ctx context.Context, input *GetTargetRequest) (*GetTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	v, err := c.read(ctx, targetCacheKey(input.TargetId), c.cfg.GetTargetTTL, func() (interface{}, error) {
+		return c.Client.GetTarget(ctx, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := *v.(*GetTargetResponse)
+	return &output, nil
+}
+
+func (c *CachingClient) TargetSummary(ctx context.Context, input *TargetSummaryRequest) (*TargetSummaryResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	v, err := c.read(ctx, summaryCacheKey(input.TargetId), c.cfg.TargetSummaryTTL, func() (interface{}, error) {
+		return c.Client.TargetSummary(ctx, input)
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := *v.(*TargetSummaryResponse)
+	return &output, nil
+}
+
+func (c *CachingClient) DatabaseSummary(ctx context.Context) (*DatabaseSummaryResponse, error) {
+	v, err := c.read(ctx, cacheDatabaseKey, c.cfg.DatabaseSummaryTTL, func() (interface{}, error) {
+		return c.Client.DatabaseSummary(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := *v.(*DatabaseSummaryResponse)
+	return &output, nil
+}
+
+func (c *CachingClient) PostTarget(ctx context.Context, input *PostTargetRequest) (*PostTargetResponse, error) {
+	resp, err := c.Client.PostTarget(ctx, input)
+	c.invalidate(cacheDatabaseKey)
+	return resp, err
+}
+
+func (c *CachingClient) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (*UpdateTargetResponse, error) {
+	resp, err := c.Client.UpdateTarget(ctx, input)
+	if input != nil {
+		c.Invalidate(input.TargetId)
+	}
+	return resp, err
+}
+
+func (c *CachingClient) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (*DeleteTargetResponse, error) {
+	resp, err := c.Client.DeleteTarget(ctx, input)
+	if input != nil {
+		c.Invalidate(input.TargetId)
+	}
+	return resp, err
+}
+
+// ListTargets is not cached; the next client must implement Lister
+func (c *CachingClient) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	return listTargets(ctx, c.Client)
+}
+
+// Duplicates is not cached; the next client must implement DuplicateFinder
+func (c *CachingClient) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	return findDuplicates(ctx, c.Client, input)
+}
+
+// Invalidate removes the entries of the targets, and of the database summary, from the cache
+func (c *CachingClient) Invalidate(targetIds ...string) {
+	keys := []string{cacheDatabaseKey}
+	for _, id := range targetIds {
+		keys = append(keys, targetCacheKey(id), summaryCacheKey(id))
+	}
+	c.invalidate(keys...)
+}
+
+// Len returns the number of entries in the cache, including the expired entries not removed yet
+func (c *CachingClient) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Stats returns the counts of reads since the client was created
+func (c *CachingClient) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *CachingClient) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		delete(c.entries, k)
+		if _, ok := c.inflight[k]; ok {
+			c.generations[k]++
+		}
+	}
+}
+
+// read returns the cached value of the key, or the value fetched by an identical read in flight,
+// or fetches the value and caches it for the TTL
+func (c *CachingClient) read(ctx context.Context, key string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	if ttl < 0 {
+		c.mu.Lock()
+		c.stats.Misses++
+		c.mu.Unlock()
+		return fetch()
+	}
+
+	for {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			if c.cfg.Clock.Now().Before(e.expires) {
+				c.stats.Hits++
+				c.mu.Unlock()
+				return e.value, nil
+			}
+			delete(c.entries, key)
+		}
+
+		call, ok := c.inflight[key]
+		if !ok {
+			return c.fetch(key, ttl, fetch)
+		}
+
+		c.stats.Shared++
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// The read in flight was cancelled by its own context, which must not fail this read
+		if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+			continue
+		}
+		return call.value, call.err
+	}
+}
+
+// fetch reads the value for the reads of the key in flight; the lock must be held and is released
+func (c *CachingClient) fetch(key string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	// The error is kept if fetch panics
+	call := &cacheCall{done: make(chan struct{}), err: errCacheFetchPanicked}
+	c.inflight[key] = call
+	generation := c.generations[key]
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil && c.generations[key] == generation {
+			now := c.cfg.Clock.Now()
+			c.sweep(now)
+			c.entries[key] = cacheEntry{value: call.value, expires: now.Add(ttl)}
+		}
+		delete(c.generations, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fetch()
+	return call.value, call.err
+}
+
+// sweep removes the expired entries if the shortest TTL elapsed since the last sweep; the lock must be held
+func (c *CachingClient) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.nextSweep = now.Add(c.sweepInterval)
+}
+
+func targetCacheKey(targetId string) string {
+	return "target/" + targetId
+}
+
+func summaryCacheKey(targetId string) string {
+	return "summary/" + targetId
+}
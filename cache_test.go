@@ -0,0 +1,158 @@
+package vuforia_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// blockingClient holds the GetTarget calls until released
+type blockingClient struct {
+	vuforia.Client
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c blockingClient) GetTarget(ctx context.Context, input *vuforia.GetTargetRequest) (*vuforia.GetTargetResponse, error) {
+	c.started <- struct{}{}
+	select {
+	case <-c.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return c.Client.GetTarget(ctx, input)
+}
+
+func TestCachingClient(t *testing.T) {
+	f := newFakeVWS(t)
+	id := f.addTarget("target", 0, "success")
+	clock := &fakeClock{now: time.Now()}
+	client := vuforia.NewCachingClient(f.client(t, vuforia.ClientConfig{}), vuforia.CacheConfig{
+		GetTargetTTL:       time.Minute,
+		DatabaseSummaryTTL: -1,
+		Clock:              clock,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		output, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+		require.NoError(t, err)
+		require.Equal(t, "target", output.TargetRecord.Name)
+		output.TargetRecord.Name = "changed by the caller"
+
+		_, err = client.TargetSummary(ctx, &vuforia.TargetSummaryRequest{TargetId: id})
+		require.NoError(t, err)
+		_, err = client.DatabaseSummary(ctx)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, f.requestCount("/targets/"+id))
+	require.Equal(t, 1, f.requestCount("/summary/"+id))
+	require.Equal(t, 3, f.requestCount("/summary"))
+	require.Equal(t, vuforia.CacheStats{Hits: 4, Misses: 5}, client.Stats())
+
+	t.Run("Expiry", func(t *testing.T) {
+		clock.After(time.Minute)
+		_, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+		require.NoError(t, err)
+		require.Equal(t, 2, f.requestCount("/targets/"+id))
+		_, err = client.TargetSummary(ctx, &vuforia.TargetSummaryRequest{TargetId: id})
+		require.NoError(t, err)
+		require.Equal(t, 1, f.requestCount("/summary/"+id))
+	})
+
+	t.Run("Sweep", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := vuforia.NewCachingClient(f.client(t, vuforia.ClientConfig{}), vuforia.CacheConfig{Clock: clock})
+		first, second := f.addTarget("first", 0, "success"), f.addTarget("second", 0, "success")
+
+		_, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: first})
+		require.NoError(t, err)
+		_, err = client.TargetSummary(ctx, &vuforia.TargetSummaryRequest{TargetId: first})
+		require.NoError(t, err)
+		require.Equal(t, 2, client.Len())
+
+		// The entries of targets no longer read are removed once expired
+		clock.After(5 * time.Minute)
+		_, err = client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: second})
+		require.NoError(t, err)
+		require.Equal(t, 1, client.Len())
+	})
+
+	t.Run("Invalidation", func(t *testing.T) {
+		name := "renamed"
+		_, err := client.UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: id, Name: &name})
+		require.NoError(t, err)
+		f.update(id, func(t *fakeTarget) { t.status = "success" })
+
+		output, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+		require.NoError(t, err)
+		require.Equal(t, "renamed", output.TargetRecord.Name)
+		_, err = client.TargetSummary(ctx, &vuforia.TargetSummaryRequest{TargetId: id})
+		require.NoError(t, err)
+		require.Equal(t, 2, f.requestCount("/summary/"+id))
+
+		_, err = client.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: id})
+		require.NoError(t, err)
+		_, err = client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+		require.Error(t, err)
+	})
+
+	t.Run("Collapse", func(t *testing.T) {
+		id := f.addTarget("other", 0, "success")
+		next := blockingClient{Client: f.client(t, vuforia.ClientConfig{}), started: make(chan struct{}, 10), release: make(chan struct{})}
+		client := vuforia.NewCachingClient(next, vuforia.CacheConfig{})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				output, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+				require.NoError(t, err)
+				require.Equal(t, "other", output.TargetRecord.Name)
+			}()
+		}
+
+		<-next.started
+		require.Eventually(t, func() bool { return client.Stats().Shared == 4 }, time.Second, time.Millisecond)
+		close(next.release)
+		wg.Wait()
+
+		require.Equal(t, 1, f.requestCount("/targets/"+id))
+		require.Equal(t, vuforia.CacheStats{Misses: 1, Shared: 4}, client.Stats())
+	})
+
+	t.Run("CancelledLeader", func(t *testing.T) {
+		id := f.addTarget("cancelled", 0, "success")
+		next := blockingClient{Client: f.client(t, vuforia.ClientConfig{}), started: make(chan struct{}, 10), release: make(chan struct{})}
+		client := vuforia.NewCachingClient(next, vuforia.CacheConfig{})
+
+		leaderCtx, cancel := context.WithCancel(ctx)
+		leaderErr := make(chan error)
+		go func() {
+			_, err := client.GetTarget(leaderCtx, &vuforia.GetTargetRequest{TargetId: id})
+			leaderErr <- err
+		}()
+		<-next.started
+
+		waiterErr := make(chan error)
+		go func() {
+			_, err := client.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: id})
+			waiterErr <- err
+		}()
+		require.Eventually(t, func() bool { return client.Stats().Shared == 1 }, time.Second, time.Millisecond)
+
+		cancel()
+		require.ErrorIs(t, <-leaderErr, context.Canceled)
+
+		// The waiter reads again instead of failing with the context of the leader
+		<-next.started
+		close(next.release)
+		require.NoError(t, <-waiterErr)
+		require.Equal(t, vuforia.CacheStats{Misses: 2, Shared: 1}, client.Stats())
+	})
+}
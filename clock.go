@@ -0,0 +1,21 @@
+package vuforia
+
+import "time"
+
+// Clock tells the current time and waits for durations to elapse.
+// It allows callers, typically tests, to control the passing of time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of the system
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,139 @@
+// Command vwsbackup exports the targets of a Vuforia database to an archive, and restores the
+// missing targets of an archive into a database.
+//
+//	vwsbackup export -o backup.tar.gz [-index index.json] [-images dir]
+//	vwsbackup restore -i backup.tar.gz [-index index.json] [exported target ID...]
+//
+// The database credentials are read from the VUFORIA_ACCESS_KEY and VUFORIA_SECRET_KEY environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yznima/vuforia-client-go"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	client, err := vuforia.NewClient(vuforia.ClientConfig{
+		AccessKey:  os.Getenv("VUFORIA_ACCESS_KEY"),
+		SecretKey:  os.Getenv("VUFORIA_SECRET_KEY"),
+		MaxRetries: 2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "export":
+		err = export(ctx, client, os.Args[2:])
+	case "restore":
+		err = restore(ctx, client, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: vwsbackup export|restore [flags]")
+	os.Exit(2)
+}
+
+func export(ctx context.Context, client vuforia.Client, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "backup.tar.gz", "file to write the archive to")
+	indexPath := flags.String("index", "", "index file providing the metadata and tags of the targets")
+	images := flags.String("images", "", "directory of the images, named after the target ID or name")
+	_ = flags.Parse(args)
+
+	cfg := vuforia.ExportConfig{Client: client}
+	if *indexPath != "" {
+		index, err := vuforia.OpenIndex(*indexPath)
+		if err != nil {
+			return err
+		}
+		cfg.Index = index
+	}
+	if *images != "" {
+		cfg.Images = vuforia.DirImageSource(*images)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	manifest, err := vuforia.ExportDatabase(ctx, f, cfg)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	var withImage int
+	for _, t := range manifest.Targets {
+		if t.Image != "" {
+			withImage++
+		}
+	}
+	log.Printf("exported %d targets, %d with image, to %s", len(manifest.Targets), withImage, *output)
+	return nil
+}
+
+func restore(ctx context.Context, client vuforia.Client, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := flags.String("i", "backup.tar.gz", "archive to restore")
+	indexPath := flags.String("index", "", "index file to add the restored targets to")
+	_ = flags.Parse(args)
+
+	cfg := vuforia.RestoreConfig{Client: client, TargetIds: flags.Args()}
+	if *indexPath != "" {
+		index, err := vuforia.OpenIndex(*indexPath)
+		if err != nil {
+			return err
+		}
+		cfg.Index = index
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := vuforia.RestoreDatabase(ctx, f, cfg)
+	if err != nil {
+		return err
+	}
+
+	for old, id := range report.Restored {
+		fmt.Printf("%s\t%s\n", old, id)
+	}
+	for _, id := range report.NoImage {
+		log.Printf("target %s cannot be restored: exported without image", id)
+	}
+	for id, err := range report.Failed {
+		log.Printf("restoring target %s failed: %v", id, err)
+	}
+	log.Printf("restored %d targets, %d already exist", len(report.Restored), len(report.Existing))
+	if len(report.Failed) > 0 || len(report.NoImage) > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Command vwsexporter serves Prometheus metrics about the recognitions of the targets of a Vuforia database.
+//
+// The database credentials are read from the VUFORIA_ACCESS_KEY and VUFORIA_SECRET_KEY environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func main() {
+	listen := flag.String("listen", ":9108", "address to serve the metrics on")
+	interval := flag.Duration("interval", 15*time.Minute, "time between two refreshes of the summaries")
+	budget := flag.Int("budget", 0, "maximum number of requests per refresh (0 for unlimited)")
+	namespace := flag.String("namespace", "", "namespace of the metrics")
+	flag.Parse()
+
+	metrics := vuforia.NewMetrics(*namespace)
+	client, err := vuforia.NewClient(vuforia.ClientConfig{
+		AccessKey:  os.Getenv("VUFORIA_ACCESS_KEY"),
+		SecretKey:  os.Getenv("VUFORIA_SECRET_KEY"),
+		Metrics:    metrics,
+		MaxRetries: 2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exporter, err := vuforia.NewExporter(vuforia.ExporterConfig{
+		Client:        client,
+		Namespace:     *namespace,
+		Interval:      *interval,
+		RequestBudget: *budget,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exporter, metrics, collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		_ = exporter.Run(ctx, func(err error) {
+			log.Printf("refresh failed: %v", err)
+		})
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: *listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("serving metrics on %s", *listen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+// Command vwsproxy exposes the Vuforia Web Services API of a database to internal services without
+// sharing the credentials of the database.
+//
+// The services call the proxy on the paths of the VWS API with their own bearer token. The callers file
+// is a JSON array of vuforia.ProxyCaller, with the tokens hashed, e.g. by `printf %s "$TOKEN" | sha256sum`.
+// Every call is written to the audit log as a JSON line.
+//
+// The database credentials are read from the VUFORIA_ACCESS_KEY and VUFORIA_SECRET_KEY environment variables.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/yznima/vuforia-client-go"
+)
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to serve the proxy on")
+	callersPath := flag.String("callers", "callers.json", "file of the callers allowed to use the proxy")
+	auditPath := flag.String("audit", "", "file to append the audit log to (defaults to stdout)")
+	flag.Parse()
+
+	data, err := ioutil.ReadFile(*callersPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var callers []vuforia.ProxyCaller
+	if err := json.Unmarshal(data, &callers); err != nil {
+		log.Fatalf("reading %s: %v", *callersPath, err)
+	}
+
+	var audit io.Writer = os.Stdout
+	if *auditPath != "" {
+		f, err := os.OpenFile(*auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		audit = f
+	}
+
+	client, err := vuforia.NewClient(vuforia.ClientConfig{
+		AccessKey:  os.Getenv("VUFORIA_ACCESS_KEY"),
+		SecretKey:  os.Getenv("VUFORIA_SECRET_KEY"),
+		MaxRetries: 2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(audit)
+	proxy, err := vuforia.NewProxy(vuforia.ProxyConfig{
+		Client:  client,
+		Callers: callers,
+		Audit: func(e vuforia.ProxyAuditEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := enc.Encode(e); err != nil {
+				log.Printf("writing audit log failed: %v", err)
+			}
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *listen, Handler: proxy, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	log.Printf("serving the proxy on %s for %d callers", *listen, len(callers))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+// Command vwsscheduler activates and deactivates the targets of a Vuforia database according to
+// the activation windows of a schedule file.
+//
+// It runs until interrupted, or reconciles the targets once with -once, e.g. from cron.
+// The database credentials are read from the VUFORIA_ACCESS_KEY and VUFORIA_SECRET_KEY environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yznima/vuforia-client-go"
+)
+
+func main() {
+	path := flag.String("schedule", "schedule.json", "file of the activation windows")
+	once := flag.Bool("once", false, "reconcile the targets once and exit")
+	interval := flag.Duration("interval", time.Hour, "maximum time between two reconciliations")
+	flag.Parse()
+
+	client, err := vuforia.NewClient(vuforia.ClientConfig{
+		AccessKey:  os.Getenv("VUFORIA_ACCESS_KEY"),
+		SecretKey:  os.Getenv("VUFORIA_SECRET_KEY"),
+		MaxRetries: 2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	schedule, err := vuforia.OpenSchedule(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	scheduler, err := vuforia.NewScheduler(vuforia.SchedulerConfig{
+		Client:   client,
+		Schedule: schedule,
+		Interval: *interval,
+		OnError: func(err error) {
+			log.Print(err)
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !*once {
+		log.Printf("scheduling the targets of %s", *path)
+		if err := scheduler.Run(ctx); err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	report, err := scheduler.Reconcile(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, id := range report.Activated {
+		log.Printf("activated %s", id)
+	}
+	for _, id := range report.Deactivated {
+		log.Printf("deactivated %s", id)
+	}
+	for _, id := range report.Removed {
+		log.Printf("removed %s, no longer in the database", id)
+	}
+	for id, err := range report.Failed {
+		log.Printf("reconciling target %s failed: %v", id, err)
+	}
+	if len(report.Failed) > 0 {
+		os.Exit(1)
+	}
+}
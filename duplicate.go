@@ -0,0 +1,273 @@
+package vuforia
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decode JPEG target images
+	_ "image/png"  // decode PNG target images
+	"math/bits"
+	"strconv"
+)
+
+// ResultCodeDuplicateTarget is the result code of the responses returned by the DuplicateGuard
+// instead of uploading a duplicate image
+const ResultCodeDuplicateTarget = "DuplicateTarget"
+
+type DuplicatePolicy int
+
+const (
+	// DuplicateReject fails the upload of a duplicate with a DuplicateTargetError
+	DuplicateReject DuplicatePolicy = iota
+	// DuplicateWarn uploads the duplicate after reporting it to OnDuplicate
+	DuplicateWarn
+	// DuplicateReturnExisting returns the existing target instead of uploading the duplicate
+	DuplicateReturnExisting
+)
+
+// DuplicateTargetError describes an image already uploaded as another target
+type DuplicateTargetError struct {
+	// TargetId and Name of the existing target
+	TargetId, Name string
+	// Exact indicates the images are identical; otherwise they are perceptually similar
+	Exact bool
+	// Distance is the number of bits differing between the perceptual hashes of the images
+	Distance int
+}
+
+func (e DuplicateTargetError) Error() string {
+	if e.Exact {
+		return fmt.Sprintf("image already uploaded as target %s (%s)", e.TargetId, e.Name)
+	}
+	return fmt.Sprintf("image similar to target %s (%s), perceptual distance %d", e.TargetId, e.Name, e.Distance)
+}
+
+type DuplicateGuardConfig struct {
+	// Index holds the hashes of the known targets; the guard records the hashes of the targets it uploads.
+	// The check and the upload are not atomic, so uploads must go through a single guard at a time.
+	Index *Index
+	// Policy is applied when a duplicate is found (Optional)
+	// Defaults to DuplicateReject.
+	Policy DuplicatePolicy
+	// MaxDistance is the maximum number of bits differing between perceptual hashes of duplicates (Optional)
+	// Defaults to 4; 0 only matches identical perceptual hashes.
+	MaxDistance *int
+	// DisablePerceptual compares the images by exact hash only (Optional)
+	DisablePerceptual bool
+	// OnDuplicate is called with every duplicate found (Optional)
+	OnDuplicate func(DuplicateTargetError)
+}
+
+// duplicateGuard checks the images against the known targets before uploading them
+type duplicateGuard struct {
+	Client
+	cfg         DuplicateGuardConfig
+	maxDistance int
+}
+
+// NewDuplicateGuard returns a Client preventing the upload of images already uploaded as other targets.
+// Images are compared by exact hash and by perceptual hash, for JPEG and PNG images.
+func NewDuplicateGuard(next Client, cfg DuplicateGuardConfig) (Client, error) {
+	if cfg.Index == nil {
+		return nil, errors.New("duplicate guard Index must be set")
+	}
+
+	maxDistance := 4
+	if cfg.MaxDistance != nil {
+		if *cfg.MaxDistance < 0 {
+			return nil, errors.New("duplicate guard MaxDistance must not be negative")
+		}
+		maxDistance = *cfg.MaxDistance
+	}
+
+	return &duplicateGuard{Client: next, cfg: cfg, maxDistance: maxDistance}, nil
+}
+
+func (g *duplicateGuard) PostTarget(ctx context.Context, input *PostTargetRequest) (*PostTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	exact, perceptual, err := g.hashes(input.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	if dup := g.findDuplicate(exact, perceptual); dup != nil {
+		if g.cfg.OnDuplicate != nil {
+			g.cfg.OnDuplicate(*dup)
+		}
+
+		switch g.cfg.Policy {
+		case DuplicateWarn:
+		case DuplicateReturnExisting:
+			return &PostTargetResponse{TargetId: dup.TargetId, ResultCode: ResultCodeDuplicateTarget}, nil
+		default:
+			return nil, *dup
+		}
+	}
+
+	resp, err := g.Client.PostTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.record(resp.TargetId, exact, perceptual, func(r *IndexRecord) {
+		if r.Name == "" {
+			r.Name, r.Width = input.Name, input.Width
+			r.Active = input.Active == nil || *input.Active
+		}
+	}); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (g *duplicateGuard) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (*UpdateTargetResponse, error) {
+	if input == nil || input.Image == nil {
+		return g.Client.UpdateTarget(ctx, input)
+	}
+
+	exact, perceptual, err := g.hashes(*input.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.Client.UpdateTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.record(input.TargetId, exact, perceptual, func(*IndexRecord) {}); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (g *duplicateGuard) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (*DeleteTargetResponse, error) {
+	resp, err := g.Client.DeleteTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Otherwise the image of the deleted target could not be uploaded again
+	if err := g.cfg.Index.Delete(input.TargetId); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (g *duplicateGuard) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	return listTargets(ctx, g.Client)
+}
+
+func (g *duplicateGuard) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	return findDuplicates(ctx, g.Client, input)
+}
+
+func (g *duplicateGuard) hashes(image string) (string, string, error) {
+	exact, err := HashImage(image)
+	if err != nil {
+		return "", "", err
+	}
+
+	var perceptual string
+	if !g.cfg.DisablePerceptual {
+		// Images that cannot be decoded are compared by exact hash only
+		perceptual, _ = PerceptualHashImage(image)
+	}
+	return exact, perceptual, nil
+}
+
+func (g *duplicateGuard) record(targetId, exact, perceptual string, fn func(*IndexRecord)) error {
+	return g.cfg.Index.Update(targetId, func(r *IndexRecord) {
+		fn(r)
+		r.ImageHash, r.PerceptualHash = exact, perceptual
+	})
+}
+
+// findDuplicate returns the known target with the same image, or the most similar one within MaxDistance
+func (g *duplicateGuard) findDuplicate(exact, perceptual string) *DuplicateTargetError {
+	if records := g.cfg.Index.ByImageHash(exact); len(records) > 0 {
+		return &DuplicateTargetError{TargetId: records[0].TargetId, Name: records[0].Name, Exact: true}
+	}
+
+	if perceptual == "" {
+		return nil
+	}
+
+	var best *DuplicateTargetError
+	for _, r := range g.cfg.Index.Find(func(r IndexRecord) bool { return r.PerceptualHash != "" }) {
+		d, err := hammingDistance(perceptual, r.PerceptualHash)
+		if err != nil || d > g.maxDistance || (best != nil && d >= best.Distance) {
+			continue
+		}
+		best = &DuplicateTargetError{TargetId: r.TargetId, Name: r.Name, Distance: d}
+	}
+	return best
+}
+
+// PerceptualHashImage returns the hex encoded 64-bit difference hash of a base64 encoded JPEG or PNG image.
+// Images that look alike have hashes differing by few bits, regardless of their size or encoding.
+func PerceptualHashImage(encoded string) (string, error) {
+	data, err := decodeImage(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	// Shrink the image to 9x8 grayscale cells, then compare every cell with its right neighbour
+	const w, h = 9, 8
+	var cells [h][w]float64
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			if y1 == y0 {
+				y1 = y0 + 1
+			}
+
+			var sum float64
+			for py := y0; py < y1; py++ {
+				for px := x0; px < x1; px++ {
+					r, g, bl, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+				}
+			}
+			cells[y][x] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash), nil
+}
+
+func hammingDistance(a, b string) (int, error) {
+	x, err := strconv.ParseUint(a, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.ParseUint(b, 16, 64)
+	if err != nil {
+		return 0, err
+	}
+	return bits.OnesCount64(x ^ y), nil
+}
@@ -0,0 +1,163 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// testImage returns a base64 encoded image of a gradient, mirrored when flip is set
+func testImage(t *testing.T, size int, flip, asJPEG bool) string {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			v := (x*255/size + y*64/size) % 256
+			if flip {
+				v = 255 - v
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(v)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if asJPEG {
+		require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70}))
+	} else {
+		require.NoError(t, png.Encode(&buf, img))
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDuplicateGuard(t *testing.T) {
+	f := newFakeVWS(t)
+	path := filepath.Join(t.TempDir(), "index.json")
+	index, err := vuforia.OpenIndex(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{})
+	require.Error(t, err)
+
+	var warnings []vuforia.DuplicateTargetError
+	guard, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{
+		Index:       index,
+		OnDuplicate: func(e vuforia.DuplicateTargetError) { warnings = append(warnings, e) },
+	})
+	require.NoError(t, err)
+
+	original := testImage(t, 64, false, false)
+	post, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "a", Width: 1, Image: original})
+	require.NoError(t, err)
+	r, ok := index.Get(post.TargetId)
+	require.True(t, ok)
+	require.Equal(t, "a", r.Name)
+	require.NotEmpty(t, r.ImageHash)
+	require.Len(t, r.PerceptualHash, 16)
+
+	_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "b", Width: 1, Image: original})
+	var dup vuforia.DuplicateTargetError
+	require.True(t, errors.As(err, &dup))
+	require.Equal(t, vuforia.DuplicateTargetError{TargetId: post.TargetId, Name: "a", Exact: true}, dup)
+
+	// The same picture resized and encoded differently
+	_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "b", Width: 1, Image: testImage(t, 96, false, true)})
+	require.True(t, errors.As(err, &dup))
+	require.False(t, dup.Exact)
+	require.Equal(t, post.TargetId, dup.TargetId)
+	require.Len(t, warnings, 2)
+
+	different, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "c", Width: 1, Image: testImage(t, 64, true, false)})
+	require.NoError(t, err)
+	require.Equal(t, 2, f.requestCount("/targets"))
+
+	t.Run("ReopenedIndex", func(t *testing.T) {
+		other, err := vuforia.OpenIndex(path)
+		require.NoError(t, err)
+		guard, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{
+			Index:  other,
+			Policy: vuforia.DuplicateReturnExisting,
+		})
+		require.NoError(t, err)
+
+		resp, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "d", Width: 1, Image: original})
+		require.NoError(t, err)
+		require.Equal(t, post.TargetId, resp.TargetId)
+		require.Equal(t, vuforia.ResultCodeDuplicateTarget, resp.ResultCode)
+
+		resp, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "d", Width: 1, Image: testImage(t, 64, true, false)})
+		require.NoError(t, err)
+		require.Equal(t, different.TargetId, resp.TargetId)
+	})
+
+	t.Run("MaxDistance", func(t *testing.T) {
+		negative := -1
+		_, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{Index: index, MaxDistance: &negative})
+		require.Error(t, err)
+
+		index, err := vuforia.OpenIndex(filepath.Join(t.TempDir(), "index.json"))
+		require.NoError(t, err)
+		zero := 0
+		guard, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{Index: index, MaxDistance: &zero})
+		require.NoError(t, err)
+
+		_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "g", Width: 1, Image: original})
+		require.NoError(t, err)
+		_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "h", Width: 1, Image: testImage(t, 64, false, true)})
+		require.True(t, errors.As(err, &dup), "the JPEG encoding has the same perceptual hash")
+		require.False(t, dup.Exact)
+		require.Zero(t, dup.Distance)
+		_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "h", Width: 1, Image: testImage(t, 96, false, true)})
+		require.NoError(t, err, "the resized image differs by a bit")
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		index, err := vuforia.OpenIndex(filepath.Join(t.TempDir(), "index.json"))
+		require.NoError(t, err)
+		guard, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{
+			Index:  index,
+			Policy: vuforia.DuplicateReturnExisting,
+		})
+		require.NoError(t, err)
+
+		first, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "i", Width: 1, Image: original})
+		require.NoError(t, err)
+		f.update(first.TargetId, func(t *fakeTarget) { t.status = "success" })
+		_, err = guard.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: first.TargetId})
+		require.NoError(t, err)
+		_, ok := index.Get(first.TargetId)
+		require.False(t, ok)
+
+		second, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "i", Width: 1, Image: original})
+		require.NoError(t, err)
+		require.NotEqual(t, vuforia.ResultCodeDuplicateTarget, second.ResultCode)
+		require.NotEqual(t, first.TargetId, second.TargetId)
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		guard, err := vuforia.NewDuplicateGuard(f.client(t, vuforia.ClientConfig{}), vuforia.DuplicateGuardConfig{
+			Index:             index,
+			Policy:            vuforia.DuplicateWarn,
+			DisablePerceptual: true,
+		})
+		require.NoError(t, err)
+
+		resp, err := guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "e", Width: 1, Image: testImage(t, 96, false, true)})
+		require.NoError(t, err, "perceptual comparison is disabled")
+		_, err = guard.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "f", Width: 1, Image: original})
+		require.NoError(t, err)
+
+		r, ok := index.Get(resp.TargetId)
+		require.True(t, ok)
+		require.Empty(t, r.PerceptualHash)
+	})
+}
@@ -2,6 +2,7 @@ package vuforia
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -9,6 +10,8 @@ import (
 type APIError struct {
 	ResultCode    string `json:"result_code"`
 	TransactionId string `json:"transaction_id"`
+	// StatusCode is the HTTP status VWS responded with; 0 for the errors not returned by VWS
+	StatusCode int `json:"-"`
 }
 
 func (e APIError) Error() string {
@@ -34,10 +37,19 @@ var vuforiaAPIErrors = map[string]string{
 	"Fail":                   "The request was invalid and could not be processed (Check the request headers and fields)",
 }
 
+// ServerError is returned when the Vuforia Web Services API encounters an internal error
+type ServerError struct {
+	StatusCode int
+}
+
+func (e ServerError) Error() string {
+	return fmt.Sprintf("the server encountered an internal error (Status = %d); please retry the request", e.StatusCode)
+}
+
 func checkError(resp *http.Response) error {
 	switch {
 	case isServerError(resp.StatusCode):
-		return fmt.Errorf("the server encountered an internal error (Status = %d); please retry the request", resp.StatusCode)
+		return ServerError{StatusCode: resp.StatusCode}
 	case isAPIError(resp.StatusCode):
 		var e APIError
 		err := json.NewDecoder(resp.Body).Decode(&e)
@@ -45,6 +57,7 @@ func checkError(resp *http.Response) error {
 			return err
 		}
 
+		e.StatusCode = resp.StatusCode
 		return e
 	default:
 		return nil
@@ -58,3 +71,9 @@ func isServerError(status int) bool {
 func isAPIError(status int) bool {
 	return status >= 400 && status < 500
 }
+
+// isRetryable reports whether the request that failed with err may succeed when retried
+func isRetryable(err error) bool {
+	var se ServerError
+	return errors.As(err, &se)
+}
@@ -0,0 +1,234 @@
+package vuforia
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ExporterConfig struct {
+	// Client is used to retrieve the summaries
+	Client Client
+	// Namespace is prepended to the names of the metrics (Optional)
+	Namespace string
+	// Interval is the time between two refreshes of the summaries (Optional)
+	// Defaults to 15 minutes.
+	Interval time.Duration
+	// RequestBudget is the maximum number of requests sent per refresh (Optional)
+	// Targets not refreshed within the budget are refreshed first on the next refresh.
+	// Every refresh requires at least 2 requests to list the targets and summarize the database.
+	RequestBudget int
+}
+
+// Exporter periodically collects the summaries of the targets and the database and
+// exposes them as Prometheus metrics. It implements prometheus.Collector.
+type Exporter struct {
+	cfg ExporterConfig
+
+	mu          sync.Mutex
+	database    *DatabaseSummaryResponse
+	targets     map[string]*TargetSummaryResponse
+	pending     []string
+	lastRefresh time.Time
+
+	targetRecos    *prometheus.Desc
+	targetRating   *prometheus.Desc
+	targetStatus   *prometheus.Desc
+	targetActive   *prometheus.Desc
+	databaseRecos  *prometheus.Desc
+	databaseImages *prometheus.Desc
+	refreshTime    *prometheus.Desc
+}
+
+func NewExporter(cfg ExporterConfig) (*Exporter, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("exporter Client must be set")
+	}
+
+	if _, ok := cfg.Client.(Lister); !ok {
+		return nil, errors.New("exporter Client must implement Lister")
+	}
+
+	if cfg.Interval == 0 {
+		cfg.Interval = 15 * time.Minute
+	}
+
+	if cfg.Interval < 0 {
+		return nil, errors.New("exporter Interval must not be negative")
+	}
+
+	if cfg.RequestBudget != 0 && cfg.RequestBudget < 3 {
+		return nil, errors.New("exporter RequestBudget must allow at least 3 requests")
+	}
+
+	name := func(n string) string {
+		return prometheus.BuildFQName(cfg.Namespace, "vuforia", n)
+	}
+	targetLabels := []string{"target_id", "target_name"}
+	return &Exporter{
+		cfg:     cfg,
+		targets: map[string]*TargetSummaryResponse{},
+		targetRecos: prometheus.NewDesc(name("target_recos"),
+			"Number of recognitions of the target in the period.", append(targetLabels, "period"), nil),
+		targetRating: prometheus.NewDesc(name("target_tracking_rating"),
+			"Tracking rating of the target image, -1 if not rated yet.", targetLabels, nil),
+		targetStatus: prometheus.NewDesc(name("target_status"),
+			"Status of the target; 1 for the current status.", append(targetLabels, "status"), nil),
+		targetActive: prometheus.NewDesc(name("target_active"),
+			"Whether the target is active for query.", targetLabels, nil),
+		databaseRecos: prometheus.NewDesc(name("database_recos"),
+			"Number of recognitions in the database in the period.", []string{"database", "period"}, nil),
+		databaseImages: prometheus.NewDesc(name("database_images"),
+			"Number of images in the database per state.", []string{"database", "state"}, nil),
+		refreshTime: prometheus.NewDesc(name("exporter_last_refresh_timestamp_seconds"),
+			"Time of the last successful refresh.", nil, nil),
+	}, nil
+}
+
+// Run refreshes the summaries every interval until the context is done.
+// Failed refreshes are reported to onError, if not nil, and retried at the next interval.
+func (e *Exporter) Run(ctx context.Context, onError func(error)) error {
+	for {
+		if err := e.Refresh(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.cfg.Interval):
+		}
+	}
+}
+
+// Refresh lists the targets and retrieves the summaries within the request budget.
+// It must not be called concurrently.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	list, err := listTargets(ctx, e.cfg.Client)
+	if err != nil {
+		return err
+	}
+
+	database, err := e.cfg.Client.DatabaseSummary(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.database = database
+	current := make(map[string]bool, len(list.Results))
+	for _, id := range list.Results {
+		current[id] = true
+	}
+	for id := range e.targets {
+		if !current[id] {
+			delete(e.targets, id)
+		}
+	}
+	e.pending = nextRefresh(e.pending, list.Results, current)
+	pending := e.pending
+	e.mu.Unlock()
+
+	if e.cfg.RequestBudget > 0 && len(pending) > e.cfg.RequestBudget-2 {
+		pending = pending[:e.cfg.RequestBudget-2]
+	}
+
+	for _, id := range pending {
+		summary, err := e.cfg.Client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: id})
+		if err != nil {
+			var ae APIError
+			if !errors.As(err, &ae) || ae.ResultCode != "UnknownTarget" {
+				return err
+			}
+		}
+
+		e.mu.Lock()
+		if summary != nil {
+			e.targets[id] = summary
+		} else {
+			delete(e.targets, id)
+		}
+		e.pending = e.pending[1:]
+		e.mu.Unlock()
+	}
+
+	e.mu.Lock()
+	e.lastRefresh = time.Now()
+	e.mu.Unlock()
+	return nil
+}
+
+// nextRefresh returns the targets to refresh in order: the ones left over from the previous refresh,
+// followed by all the other current targets
+func nextRefresh(leftover, ids []string, current map[string]bool) []string {
+	seen := map[string]bool{}
+	var next []string
+	for _, id := range leftover {
+		if current[id] && !seen[id] {
+			seen[id] = true
+			next = append(next, id)
+		}
+	}
+
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	for _, id := range sorted {
+		if !seen[id] {
+			seen[id] = true
+			next = append(next, id)
+		}
+	}
+	return next
+}
+
+// Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.targetRecos
+	ch <- e.targetRating
+	ch <- e.targetStatus
+	ch <- e.targetActive
+	ch <- e.databaseRecos
+	ch <- e.databaseImages
+	ch <- e.refreshTime
+}
+
+// Collect implements prometheus.Collector
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	gauge := func(desc *prometheus.Desc, v int, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), labels...)
+	}
+
+	for id, t := range e.targets {
+		gauge(e.targetRecos, t.TotalRecos, id, t.TargetName, "total")
+		gauge(e.targetRecos, t.CurrentMonthRecos, id, t.TargetName, "current_month")
+		gauge(e.targetRecos, t.PreviousMonthRecos, id, t.TargetName, "previous_month")
+		gauge(e.targetRating, int(t.TrackingRating), id, t.TargetName)
+		gauge(e.targetStatus, 1, id, t.TargetName, string(t.Status))
+		active := 0
+		if t.Active {
+			active = 1
+		}
+		gauge(e.targetActive, active, id, t.TargetName)
+	}
+
+	if db := e.database; db != nil {
+		gauge(e.databaseRecos, db.TotalRecos, db.Name, "total")
+		gauge(e.databaseRecos, db.CurrentMonthRecos, db.Name, "current_month")
+		gauge(e.databaseRecos, db.PreviousMonthRecos, db.Name, "previous_month")
+		gauge(e.databaseImages, db.ActiveImages, db.Name, "active")
+		gauge(e.databaseImages, db.InactiveImages, db.Name, "inactive")
+		gauge(e.databaseImages, db.FailedImages, db.Name, "failed")
+		gauge(e.databaseImages, db.ProcessingImages, db.Name, "processing")
+	}
+
+	if !e.lastRefresh.IsZero() {
+		ch <- prometheus.MustNewConstMetric(e.refreshTime, prometheus.GaugeValue, float64(e.lastRefresh.Unix()))
+	}
+}
@@ -0,0 +1,43 @@
+package vuforia_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestExporter(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		ids = append(ids, f.addTarget(name, 0, "success"))
+	}
+
+	exporter, err := vuforia.NewExporter(vuforia.ExporterConfig{Client: client, RequestBudget: 4})
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.Refresh(context.Background()))
+	require.Equal(t, 1, f.requestCount("/targets"))
+	require.Equal(t, 1, f.requestCount("/summary"))
+	require.Equal(t, 2*3, testutil.CollectAndCount(exporter, "vuforia_target_recos"))
+
+	require.NoError(t, exporter.Refresh(context.Background()))
+	require.Equal(t, 3*3, testutil.CollectAndCount(exporter, "vuforia_target_recos"))
+	require.Equal(t, 3, testutil.CollectAndCount(exporter, "vuforia_target_tracking_rating"))
+	require.Equal(t, 4, testutil.CollectAndCount(exporter, "vuforia_database_images"))
+	require.Equal(t, 2, f.requestCount("/summary/"+ids[0]), "the target refreshed first is refreshed again once all have been")
+	require.Equal(t, 1, f.requestCount("/summary/"+ids[2]))
+
+	_, err = vuforia.NewExporter(vuforia.ExporterConfig{Client: client, RequestBudget: 2})
+	require.Error(t, err)
+	_, err = vuforia.NewExporter(vuforia.ExporterConfig{Client: client, Interval: -1})
+	require.Error(t, err)
+
+	// The targets cannot be listed through a client not implementing Lister
+	_, err = vuforia.NewExporter(vuforia.ExporterConfig{Client: struct{ vuforia.Client }{client}})
+	require.Error(t, err)
+}
@@ -0,0 +1,358 @@
+package vuforia_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// fakeVWS is an in-memory implementation of the Vuforia Web Services API
+type fakeVWS struct {
+	mu      sync.Mutex
+	server  *httptest.Server
+	targets map[string]*fakeTarget
+	nextId  int
+	nextTx  int
+	// failures are returned, in order, instead of handling the next requests
+	failures []fakeFailure
+	// requests counts the requests received per path
+	requests map[string]int
+	// imageRatings are the tracking ratings of the images once successfully processed; 5 when not set
+	imageRatings map[string]int
+	// failingImages fail processing
+	failingImages map[string]bool
+	// imagePolls are the polls of the targets posted with the images before they leave the processing state
+	imagePolls map[string]int
+}
+
+type fakeTarget struct {
+	id, name, image, metadata string
+	width                     float64
+	active                    bool
+	status                    string
+	rating                    int
+	// polls is the number of GetTarget requests before the target leaves the processing state
+	polls int
+	// finalStatus is the status of the target once processed
+	finalStatus string
+}
+
+type fakeFailure struct {
+	status     int
+	resultCode string
+}
+
+func newFakeVWS(t *testing.T) *fakeVWS {
+	f := &fakeVWS{
+		targets:       map[string]*fakeTarget{},
+		requests:      map[string]int{},
+		imageRatings:  map[string]int{},
+		failingImages: map[string]bool{},
+		imagePolls:    map[string]int{},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns a client sending its requests to the fake
+func (f *fakeVWS) client(t *testing.T, cfg vuforia.ClientConfig) vuforia.Client {
+	u, err := url.Parse(f.server.URL)
+	require.NoError(t, err)
+
+	cfg.SecretKey, cfg.AccessKey = "secret", "access"
+	cfg.Client = &http.Client{Transport: redirectTransport{url: u}}
+	c, err := vuforia.NewClient(cfg)
+	require.NoError(t, err)
+	return c
+}
+
+// addTarget adds a target that is processed on the given GetTarget request, or immediately if 0
+func (f *fakeVWS) addTarget(name string, polls int, finalStatus string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.newTarget(name)
+	t.polls, t.finalStatus = polls, finalStatus
+	f.process(t)
+	return t.id
+}
+
+// fail makes the next request fail with the given status and result code
+func (f *fakeVWS) fail(status int, resultCode string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, fakeFailure{status: status, resultCode: resultCode})
+}
+
+// update modifies the target as if changed through another client
+func (f *fakeVWS) update(id string, fn func(t *fakeTarget)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.targets[id]; ok {
+		fn(t)
+	}
+}
+
+func (f *fakeVWS) remove(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.targets, id)
+}
+
+func (f *fakeVWS) target(id string) *fakeTarget {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.targets[id]
+}
+
+func (f *fakeVWS) requestCount(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func (f *fakeVWS) newTarget(name string) *fakeTarget {
+	f.nextId++
+	t := &fakeTarget{
+		id:          fmt.Sprintf("%032x", f.nextId),
+		name:        name,
+		active:      true,
+		status:      "processing",
+		rating:      -1,
+		finalStatus: "success",
+	}
+	f.targets[t.id] = t
+	return t
+}
+
+func (f *fakeVWS) process(t *fakeTarget) {
+	if t.status != "processing" || t.polls > 0 {
+		return
+	}
+	t.status = t.finalStatus
+	if f.failingImages[t.image] {
+		t.status = "failed"
+	}
+	if t.status != "success" {
+		t.rating = 0
+	} else if rating, ok := f.imageRatings[t.image]; ok {
+		t.rating = rating
+	} else {
+		t.rating = 5
+	}
+}
+
+func (f *fakeVWS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests[r.URL.Path]++
+	f.nextTx++
+	tx := fmt.Sprintf("tx%04d", f.nextTx)
+
+	reply := func(status int, v map[string]interface{}) {
+		v["transaction_id"] = tx
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	fail := func(status int, code string) {
+		reply(status, map[string]interface{}{"result_code": code})
+	}
+
+	if len(f.failures) > 0 {
+		failure := f.failures[0]
+		f.failures = f.failures[1:]
+		fail(failure.status, failure.resultCode)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var id string
+	if len(parts) > 1 {
+		id = parts[1]
+	}
+
+	switch {
+	case parts[0] == "targets" && id == "" && r.Method == http.MethodPost:
+		var in struct {
+			Name     string  `json:"name"`
+			Width    float64 `json:"width"`
+			Image    string  `json:"image"`
+			Active   *bool   `json:"active_flag"`
+			Metadata *string `json:"application_metadata"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			fail(http.StatusBadRequest, "Fail")
+			return
+		}
+		// Names of invalid UTF-8 are received with replacement characters
+		if utf8.RuneCountInString(in.Name) > 64 || strings.ContainsRune(in.Name, utf8.RuneError) {
+			fail(http.StatusBadRequest, "Fail")
+			return
+		}
+		for _, t := range f.targets {
+			if t.name == in.Name {
+				fail(http.StatusForbidden, "TargetNameExist")
+				return
+			}
+		}
+		t := f.newTarget(in.Name)
+		t.width, t.image, t.polls = in.Width, in.Image, f.imagePolls[in.Image]
+		if in.Active != nil {
+			t.active = *in.Active
+		}
+		if in.Metadata != nil {
+			t.metadata = *in.Metadata
+		}
+		reply(http.StatusCreated, map[string]interface{}{"result_code": "TargetCreated", "target_id": t.id})
+	case parts[0] == "targets" && id == "" && r.Method == http.MethodGet:
+		ids := []string{}
+		for id := range f.targets {
+			ids = append(ids, id)
+		}
+		reply(http.StatusOK, map[string]interface{}{"result_code": "Success", "results": ids})
+	case parts[0] == "targets" && id != "":
+		t, ok := f.targets[id]
+		if !ok {
+			fail(http.StatusNotFound, "UnknownTarget")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if t.polls > 0 {
+				t.polls--
+			}
+			f.process(t)
+			reply(http.StatusOK, map[string]interface{}{
+				"result_code": "Success",
+				"status":      t.status,
+				"target_record": map[string]interface{}{
+					"target_id":       t.id,
+					"active_flag":     t.active,
+					"name":            t.name,
+					"width":           t.width,
+					"tracking_rating": t.rating,
+				},
+			})
+		case http.MethodPut:
+			if t.status == "processing" {
+				fail(http.StatusForbidden, "TargetStatusProcessing")
+				return
+			}
+			var in struct {
+				Name     *string  `json:"name"`
+				Width    *float64 `json:"width"`
+				Image    *string  `json:"image"`
+				Active   *bool    `json:"active_flag"`
+				Metadata *string  `json:"application_metadata"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				fail(http.StatusBadRequest, "Fail")
+				return
+			}
+			if in.Name != nil {
+				t.name = *in.Name
+			}
+			if in.Width != nil {
+				t.width = *in.Width
+			}
+			if in.Active != nil {
+				t.active = *in.Active
+			}
+			if in.Metadata != nil {
+				t.metadata = *in.Metadata
+			}
+			if in.Image != nil {
+				t.image, t.status, t.rating = *in.Image, "processing", -1
+			}
+			reply(http.StatusOK, map[string]interface{}{"result_code": "Success"})
+		case http.MethodDelete:
+			if t.status == "processing" {
+				fail(http.StatusForbidden, "TargetStatusProcessing")
+				return
+			}
+			delete(f.targets, id)
+			reply(http.StatusOK, map[string]interface{}{"result_code": "Success"})
+		}
+	case parts[0] == "duplicates" && id != "":
+		t, ok := f.targets[id]
+		if !ok {
+			fail(http.StatusNotFound, "UnknownTarget")
+			return
+		}
+		similar := []string{}
+		for _, other := range f.targets {
+			if other.id != t.id && other.image == t.image {
+				similar = append(similar, other.id)
+			}
+		}
+		sort.Strings(similar)
+		reply(http.StatusOK, map[string]interface{}{"result_code": "Success", "similar_targets": similar})
+	case parts[0] == "summary" && id != "":
+		t, ok := f.targets[id]
+		if !ok {
+			fail(http.StatusNotFound, "UnknownTarget")
+			return
+		}
+		reply(http.StatusOK, map[string]interface{}{
+			"result_code":          "Success",
+			"status":               t.status,
+			"database_name":        "fake",
+			"target_name":          t.name,
+			"upload_date":          "2021-05-01",
+			"active_flag":          t.active,
+			"tracking_rating":      t.rating,
+			"total_recos":          0,
+			"current_month_recos":  0,
+			"previous_month_recos": 0,
+		})
+	case parts[0] == "summary":
+		var active, inactive, failed int
+		for _, t := range f.targets {
+			switch {
+			case t.status == "failed":
+				failed++
+			case t.status == "success" && t.active:
+				active++
+			case t.status == "success":
+				inactive++
+			}
+		}
+		reply(http.StatusOK, map[string]interface{}{
+			"result_code":     "Success",
+			"name":            "fake",
+			"active_images":   active,
+			"inactive_images": inactive,
+			"failed_images":   failed,
+			"target_quota":    1000,
+			"request_quota":   100000,
+			"request_usage":   f.nextTx,
+			"reco_threshold":  1000,
+		})
+	default:
+		fail(http.StatusNotFound, "Fail")
+	}
+}
+
+// redirectTransport sends the requests to the given URL instead of the Vuforia Web Services API
+type redirectTransport struct {
+	url *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.url.Scheme
+	req.URL.Host = t.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
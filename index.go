@@ -0,0 +1,384 @@
+package vuforia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// IndexRecord is what is known locally about a target
+type IndexRecord struct {
+	TargetId string  `json:"target_id"`
+	Name     string  `json:"name"`
+	Width    float64 `json:"width"`
+	Active   bool    `json:"active_flag"`
+	// Metadata is the base64 encoded application metadata as last written through the client
+	Metadata *string `json:"application_metadata,omitempty"`
+	// ImageHash is the hash of the image as last written through the client, see HashImage
+	ImageHash string `json:"image_hash,omitempty"`
+	// PerceptualHash is the perceptual hash of the image as last written through the DuplicateGuard, see PerceptualHashImage
+	PerceptualHash string `json:"perceptual_hash,omitempty"`
+	// Tags are set locally to group targets
+	Tags []string `json:"tags,omitempty"`
+	// UpdatedAt is the last time the record changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HasTag reports whether the record has the tag
+func (r IndexRecord) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexError is returned along with the response when an operation succeeded but the index could not be updated
+type IndexError struct {
+	Err error
+}
+
+func (e IndexError) Error() string {
+	return fmt.Sprintf("vuforia index update failed: %v", e.Err)
+}
+
+func (e IndexError) Unwrap() error {
+	return e.Err
+}
+
+// Index mirrors the targets of a database in a local JSON file, including what VWS does not return
+// such as the application metadata and the image hash.
+// The file is rewritten on every change, so it must be written by a single process at a time.
+type Index struct {
+	path string
+
+	mu      sync.RWMutex
+	records map[string]IndexRecord
+}
+
+// OpenIndex loads the index from the file, which is created on the first change if it does not exist
+func OpenIndex(path string) (*Index, error) {
+	idx := &Index{path: path, records: map[string]IndexRecord{}}
+	if err := idx.Reload(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Reload replaces the records with the ones in the file, such as written by another process
+func (i *Index) Reload() error {
+	data, err := ioutil.ReadFile(i.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var records []IndexRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.records = make(map[string]IndexRecord, len(records))
+	for _, r := range records {
+		i.records[r.TargetId] = r
+	}
+	return nil
+}
+
+// Get returns the record of the target
+func (i *Index) Get(targetId string) (IndexRecord, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	r, ok := i.records[targetId]
+	return r, ok
+}
+
+// ByName returns the record of the target with the name
+func (i *Index) ByName(name string) (IndexRecord, bool) {
+	records := i.Find(func(r IndexRecord) bool { return r.Name == name })
+	if len(records) == 0 {
+		return IndexRecord{}, false
+	}
+	return records[0], true
+}
+
+// ByTag returns the records of the targets with the tag
+func (i *Index) ByTag(tag string) []IndexRecord {
+	return i.Find(func(r IndexRecord) bool { return r.HasTag(tag) })
+}
+
+// ByImageHash returns the records of the targets whose image has the hash
+func (i *Index) ByImageHash(hash string) []IndexRecord {
+	return i.Find(func(r IndexRecord) bool { return r.ImageHash == hash })
+}
+
+// All returns all the records ordered by target ID
+func (i *Index) All() []IndexRecord {
+	return i.Find(func(IndexRecord) bool { return true })
+}
+
+// Find returns the records matching the predicate ordered by target ID
+func (i *Index) Find(match func(IndexRecord) bool) []IndexRecord {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var records []IndexRecord
+	for _, r := range i.records {
+		if match(r) {
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(a, b int) bool {
+		return records[a].TargetId < records[b].TargetId
+	})
+	return records
+}
+
+// Put adds or replaces the record
+func (i *Index) Put(r IndexRecord) error {
+	return i.Update(r.TargetId, func(existing *IndexRecord) {
+		*existing = r
+	})
+}
+
+// Update changes the record of the target, creating it if needed
+func (i *Index) Update(targetId string, fn func(*IndexRecord)) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	r, ok := i.records[targetId]
+	if !ok {
+		r = IndexRecord{TargetId: targetId}
+	}
+	fn(&r)
+	r.TargetId = targetId
+	r.UpdatedAt = time.Now().UTC()
+	i.records[targetId] = r
+	return i.save()
+}
+
+// SetTags replaces the tags of the target
+func (i *Index) SetTags(targetId string, tags ...string) error {
+	if _, ok := i.Get(targetId); !ok {
+		return fmt.Errorf("target %s is not indexed", targetId)
+	}
+	return i.Update(targetId, func(r *IndexRecord) {
+		r.Tags = append([]string(nil), tags...)
+	})
+}
+
+// Delete removes the record of the target
+func (i *Index) Delete(targetId string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.records[targetId]; !ok {
+		return nil
+	}
+	delete(i.records, targetId)
+	return i.save()
+}
+
+// save writes the records to the file; the lock must be held
+func (i *Index) save() error {
+	records := make([]IndexRecord, 0, len(i.records))
+	for _, r := range i.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(a, b int) bool {
+		return records[a].TargetId < records[b].TargetId
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(i.path, data)
+}
+
+// ReconcileReport lists the changes made to the index by Reconcile
+type ReconcileReport struct {
+	// Added are the targets missing from the index
+	Added []string
+	// Updated are the targets whose name, width or active flag differed
+	Updated []string
+	// Removed are the targets no longer in the database
+	Removed []string
+}
+
+// Reconcile updates the index with the targets of the database.
+// Reconciling an empty index rebuilds it, without the metadata and image hashes that VWS does not return.
+func (i *Index) Reconcile(ctx context.Context, client Client) (*ReconcileReport, error) {
+	list, err := listTargets(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{}
+	remote := map[string]bool{}
+	for _, id := range list.Results {
+		output, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+		if err != nil {
+			var ae APIError
+			if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+				continue
+			}
+			return report, err
+		}
+		remote[id] = true
+
+		existing, ok := i.Get(id)
+		rec := output.TargetRecord
+		switch {
+		case !ok:
+			report.Added = append(report.Added, id)
+		case existing.Name != rec.Name || existing.Width != rec.Width || existing.Active != rec.Active:
+			report.Updated = append(report.Updated, id)
+		default:
+			continue
+		}
+
+		if err := i.Update(id, func(r *IndexRecord) {
+			r.Name, r.Width, r.Active = rec.Name, rec.Width, rec.Active
+		}); err != nil {
+			return report, err
+		}
+	}
+
+	for _, r := range i.All() {
+		if !remote[r.TargetId] {
+			report.Removed = append(report.Removed, r.TargetId)
+			if err := i.Delete(r.TargetId); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// HashImage returns the hex encoded SHA-256 hash of a base64 encoded image
+func HashImage(image string) (string, error) {
+	data, err := decodeImage(image)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// decodeImage decodes a base64 encoded image, with or without padding
+func decodeImage(image string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		if raw, rawErr := base64.RawStdEncoding.DecodeString(image); rawErr == nil {
+			return raw, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// indexingClient updates an Index with every target written through it
+type indexingClient struct {
+	Client
+	index *Index
+}
+
+// NewIndexingClient returns a Client updating the index on every PostTarget, UpdateTarget and DeleteTarget.
+// When the operation succeeds but the index cannot be updated, the response is returned along with an IndexError.
+func NewIndexingClient(next Client, index *Index) Client {
+	return &indexingClient{Client: next, index: index}
+}
+
+func (c *indexingClient) PostTarget(ctx context.Context, input *PostTargetRequest) (*PostTargetResponse, error) {
+	resp, err := c.Client.PostTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	r := IndexRecord{Name: input.Name, Width: input.Width, Active: true, Metadata: input.Metadata}
+	if input.Active != nil {
+		r.Active = *input.Active
+	}
+	if input.Image != "" {
+		if r.ImageHash, err = HashImage(input.Image); err != nil {
+			return resp, IndexError{Err: err}
+		}
+	}
+	r.TargetId = resp.TargetId
+
+	if err := c.index.Put(r); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (c *indexingClient) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (*UpdateTargetResponse, error) {
+	resp, err := c.Client.UpdateTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var imageHash string
+	if input.Image != nil {
+		if imageHash, err = HashImage(*input.Image); err != nil {
+			return resp, IndexError{Err: err}
+		}
+	}
+
+	if err := c.index.Update(input.TargetId, func(r *IndexRecord) {
+		if input.Name != nil {
+			r.Name = *input.Name
+		}
+		if input.Width != nil {
+			r.Width = *input.Width
+		}
+		if input.Active != nil {
+			r.Active = *input.Active
+		}
+		if input.Metadata != nil {
+			r.Metadata = input.Metadata
+		}
+		if input.Image != nil {
+			// The perceptual hash of the replaced image must not be compared against new uploads
+			r.ImageHash, r.PerceptualHash = imageHash, ""
+		}
+	}); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (c *indexingClient) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (*DeleteTargetResponse, error) {
+	resp, err := c.Client.DeleteTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.index.Delete(input.TargetId); err != nil {
+		return resp, IndexError{Err: err}
+	}
+	return resp, nil
+}
+
+func (c *indexingClient) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	return listTargets(ctx, c.Client)
+}
+
+func (c *indexingClient) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	return findDuplicates(ctx, c.Client, input)
+}
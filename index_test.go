@@ -0,0 +1,88 @@
+package vuforia_test
+
+import (
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestIndex(t *testing.T) {
+	f := newFakeVWS(t)
+	path := filepath.Join(t.TempDir(), "index.json")
+	index, err := vuforia.OpenIndex(path)
+	require.NoError(t, err)
+	client := vuforia.NewIndexingClient(f.client(t, vuforia.ClientConfig{}), index)
+	ctx := context.Background()
+
+	image := base64.StdEncoding.EncodeToString([]byte("image"))
+	hash, err := vuforia.HashImage(image)
+	require.NoError(t, err)
+	metadata := base64.StdEncoding.EncodeToString([]byte("metadata"))
+	inactive := false
+
+	post, err := client.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "a", Width: 1, Image: image, Active: &inactive, Metadata: &metadata})
+	require.NoError(t, err)
+	other, err := client.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "b", Width: 2, Image: image})
+	require.NoError(t, err)
+
+	r, ok := index.ByName("a")
+	require.True(t, ok)
+	require.Equal(t, post.TargetId, r.TargetId)
+	require.Equal(t, 1.0, r.Width)
+	require.False(t, r.Active)
+	require.Equal(t, metadata, *r.Metadata)
+	require.Equal(t, hash, r.ImageHash)
+	require.Len(t, index.ByImageHash(hash), 2)
+
+	require.NoError(t, index.SetTags(post.TargetId, "summer", "campaign"))
+	require.Error(t, index.SetTags("unknown", "summer"))
+
+	require.NoError(t, index.Update(post.TargetId, func(r *vuforia.IndexRecord) { r.PerceptualHash = "0001010101030302" }))
+	f.update(post.TargetId, func(t *fakeTarget) { t.status = "success" })
+	name, image2 := "a2", base64.StdEncoding.EncodeToString([]byte("image2"))
+	_, err = client.UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: post.TargetId, Name: &name, Image: &image2})
+	require.NoError(t, err)
+
+	// The index is persisted
+	index, err = vuforia.OpenIndex(path)
+	require.NoError(t, err)
+	r, ok = index.Get(post.TargetId)
+	require.True(t, ok)
+	require.Equal(t, "a2", r.Name)
+	require.Equal(t, metadata, *r.Metadata)
+	require.NotEqual(t, hash, r.ImageHash)
+	require.Empty(t, r.PerceptualHash, "the perceptual hash of the replaced image is cleared")
+	require.Equal(t, []vuforia.IndexRecord{r}, index.ByTag("summer"))
+
+	client = vuforia.NewIndexingClient(f.client(t, vuforia.ClientConfig{}), index)
+	f.update(other.TargetId, func(t *fakeTarget) { t.status = "success" })
+	_, err = client.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: other.TargetId})
+	require.NoError(t, err)
+	_, ok = index.Get(other.TargetId)
+	require.False(t, ok)
+
+	t.Run("Reconcile", func(t *testing.T) {
+		unindexed := f.addTarget("c", 0, "success")
+		f.update(post.TargetId, func(t *fakeTarget) { t.width = 5 })
+		require.NoError(t, index.Put(vuforia.IndexRecord{TargetId: "gone", Name: "gone"}))
+
+		report, err := index.Reconcile(ctx, client)
+		require.NoError(t, err)
+		require.Equal(t, []string{unindexed}, report.Added)
+		require.Equal(t, []string{post.TargetId}, report.Updated)
+		require.Equal(t, []string{"gone"}, report.Removed)
+
+		r, ok := index.Get(post.TargetId)
+		require.True(t, ok)
+		require.Equal(t, 5.0, r.Width)
+		require.Equal(t, []string{"summer", "campaign"}, r.Tags, "local fields are kept")
+
+		r, ok = index.ByName("c")
+		require.True(t, ok)
+		require.Equal(t, unindexed, r.TargetId)
+	})
+}
@@ -0,0 +1,132 @@
+package vuforia
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// MaxMetadataSize is the maximum size of the base64 encoded application metadata of a target
+const MaxMetadataSize = 1024 * 1024
+
+// MaxDecompressedMetadataSize is the maximum size of the data of compressed metadata, which bounds the memory
+// used to decode malicious metadata
+const MaxDecompressedMetadataSize = 16 * MaxMetadataSize
+
+// ErrMetadataTooLarge is returned when the encoded metadata exceeds MaxMetadataSize,
+// or the data of compressed metadata exceeds MaxDecompressedMetadataSize
+var ErrMetadataTooLarge = errors.New("vuforia application metadata exceeds the maximum size")
+
+// metadataMagic prefixes the metadata compressed by EncodeRawMetadata, followed by the version of the format
+var metadataMagic = []byte("VMD")
+
+// Versions of the format of the metadata following metadataMagic
+const (
+	// metadataStored escapes uncompressed metadata starting with metadataMagic
+	metadataStored = 0
+	// metadataVersion is the version of the format of the compressed metadata
+	metadataVersion = 1
+)
+
+type MetadataOptions struct {
+	// Compress gzip-compresses the metadata behind a version header (Optional)
+	// Compressed metadata must be decoded with DecodeRawMetadata or DecodeMetadata. The data may exceed
+	// MaxMetadataSize as long as it compresses under it, up to MaxDecompressedMetadataSize.
+	Compress bool
+}
+
+// EncodeMetadata marshals v to JSON and encodes it for PostTargetRequest.Metadata or UpdateTargetRequest.Metadata
+func EncodeMetadata(v interface{}, opts MetadataOptions) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return EncodeRawMetadata(data, opts)
+}
+
+// EncodeRawMetadata encodes data for PostTargetRequest.Metadata or UpdateTargetRequest.Metadata.
+// It returns ErrMetadataTooLarge if the encoded metadata exceeds MaxMetadataSize, or if the data to compress
+// exceeds MaxDecompressedMetadataSize.
+// Uncompressed data starting with the header of compressed metadata is escaped and must be decoded with DecodeRawMetadata.
+func EncodeRawMetadata(data []byte, opts MetadataOptions) (string, error) {
+	if len(data) > MaxDecompressedMetadataSize {
+		return "", ErrMetadataTooLarge
+	}
+
+	switch {
+	case opts.Compress:
+		var buf bytes.Buffer
+		buf.Write(metadataMagic)
+		buf.WriteByte(metadataVersion)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	case bytes.HasPrefix(data, metadataMagic):
+		data = append(append(append([]byte(nil), metadataMagic...), metadataStored), data...)
+	}
+
+	if base64.StdEncoding.EncodedLen(len(data)) > MaxMetadataSize {
+		return "", ErrMetadataTooLarge
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeMetadata decodes metadata, such as returned by Cloud Recognition queries, and unmarshals its JSON into v
+func DecodeMetadata(metadata string, v interface{}) error {
+	data, err := DecodeRawMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// DecodeRawMetadata decodes metadata, such as returned by Cloud Recognition queries, decompressing it if needed.
+// Metadata encoded without base64 padding is accepted as well.
+func DecodeRawMetadata(metadata string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(metadata)
+	if err != nil {
+		var rawErr error
+		if data, rawErr = base64.RawStdEncoding.DecodeString(metadata); rawErr != nil {
+			return nil, err
+		}
+	}
+
+	if !bytes.HasPrefix(data, metadataMagic) || len(data) == len(metadataMagic) {
+		return data, nil
+	}
+
+	payload := data[len(metadataMagic)+1:]
+	switch version := data[len(metadataMagic)]; version {
+	case metadataStored:
+		return payload, nil
+	case metadataVersion:
+	default:
+		return nil, fmt.Errorf("unsupported vuforia metadata version %d", version)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	// The metadata could not have been encoded if larger, which bounds the memory used by malicious metadata
+	decoded, err := ioutil.ReadAll(io.LimitReader(zr, MaxDecompressedMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > MaxDecompressedMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	return decoded, nil
+}
@@ -0,0 +1,200 @@
+package vuforia
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Algorithms of the metadata envelopes
+const (
+	SignatureEd25519 = "Ed25519"
+	SignatureHS256   = "HS256"
+	EncryptionAESGCM = "AES-GCM"
+)
+
+// envelopeVersion is the version of the format of the metadata envelopes
+const envelopeVersion = 1
+
+// ErrInvalidSignature is returned when the signature of a metadata envelope does not match its content
+var ErrInvalidSignature = errors.New("vuforia metadata signature is invalid")
+
+// UnknownKeyError is returned when a metadata envelope references a key missing from the keyring
+type UnknownKeyError struct {
+	KeyId string
+}
+
+func (e UnknownKeyError) Error() string {
+	return fmt.Sprintf("vuforia metadata key %q is unknown", e.KeyId)
+}
+
+// envelope wraps the metadata along with its signature
+type envelope struct {
+	Version         int    `json:"v"`
+	Algorithm       string `json:"alg"`
+	KeyId           string `json:"kid"`
+	Encryption      string `json:"enc,omitempty"`
+	EncryptionKeyId string `json:"ekid,omitempty"`
+	Nonce           []byte `json:"nonce,omitempty"`
+	Payload         []byte `json:"payload"`
+	Signature       []byte `json:"sig"`
+}
+
+// signingInput returns the content of the envelope covered by the signature
+func (e envelope) signingInput() []byte {
+	enc := base64.RawURLEncoding
+	return []byte(strings.Join([]string{
+		fmt.Sprint(e.Version), e.Algorithm, e.KeyId, e.Encryption, e.EncryptionKeyId,
+		enc.EncodeToString(e.Nonce), enc.EncodeToString(e.Payload),
+	}, "."))
+}
+
+// MetadataSealer signs, and optionally encrypts, application metadata so that devices can trust it
+type MetadataSealer struct {
+	// KeyId identifies the signing key in the envelope
+	KeyId string
+	// Ed25519Key signs the metadata with Ed25519; exactly one of Ed25519Key or HMACKey must be set
+	Ed25519Key ed25519.PrivateKey
+	// HMACKey signs the metadata with HMAC-SHA256; exactly one of Ed25519Key or HMACKey must be set
+	HMACKey []byte
+	// EncryptionKeyId identifies the encryption key in the envelope (Optional)
+	EncryptionKeyId string
+	// EncryptionKey encrypts the metadata with AES-GCM; it must be 16, 24 or 32 bytes long (Optional)
+	EncryptionKey []byte
+	// Options are used to encode the envelope (Optional)
+	Options MetadataOptions
+}
+
+// Seal wraps the payload in a signed envelope and encodes it for PostTargetRequest.Metadata or UpdateTargetRequest.Metadata
+func (s MetadataSealer) Seal(payload []byte) (string, error) {
+	if (s.Ed25519Key == nil) == (s.HMACKey == nil) {
+		return "", errors.New("exactly one of Ed25519Key or HMACKey must be set")
+	}
+
+	e := envelope{Version: envelopeVersion, KeyId: s.KeyId, Payload: payload}
+	if s.EncryptionKey != nil {
+		gcm, err := newGCM(s.EncryptionKey)
+		if err != nil {
+			return "", err
+		}
+
+		e.Encryption, e.EncryptionKeyId = EncryptionAESGCM, s.EncryptionKeyId
+		e.Nonce = make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(e.Nonce); err != nil {
+			return "", err
+		}
+		e.Payload = gcm.Seal(nil, e.Nonce, payload, []byte(s.EncryptionKeyId))
+	}
+
+	if s.Ed25519Key != nil {
+		if len(s.Ed25519Key) != ed25519.PrivateKeySize {
+			return "", fmt.Errorf("Ed25519Key must be %d bytes long", ed25519.PrivateKeySize)
+		}
+		e.Algorithm = SignatureEd25519
+		e.Signature = ed25519.Sign(s.Ed25519Key, e.signingInput())
+	} else {
+		e.Algorithm = SignatureHS256
+		e.Signature = hmacSHA256(s.HMACKey, e.signingInput())
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return EncodeRawMetadata(data, s.Options)
+}
+
+// MetadataKeyring holds the keys, by ID, used to open sealed metadata.
+// Keeping the previous keys along with the current ones allows rotating the keys.
+type MetadataKeyring struct {
+	// Ed25519Keys verify the metadata signed with Ed25519
+	Ed25519Keys map[string]ed25519.PublicKey
+	// HMACKeys verify the metadata signed with HMAC-SHA256
+	HMACKeys map[string][]byte
+	// EncryptionKeys decrypt the encrypted metadata
+	EncryptionKeys map[string][]byte
+}
+
+// Open verifies the signature of sealed metadata, such as returned by Cloud Recognition queries,
+// and returns its payload, decrypted if needed
+func (k MetadataKeyring) Open(metadata string) ([]byte, error) {
+	data, err := DecodeRawMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("vuforia metadata is not sealed: %w", err)
+	}
+
+	if e.Version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported vuforia metadata envelope version %d", e.Version)
+	}
+
+	switch e.Algorithm {
+	case SignatureEd25519:
+		key, ok := k.Ed25519Keys[e.KeyId]
+		if !ok {
+			return nil, UnknownKeyError{KeyId: e.KeyId}
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("vuforia metadata key %q must be %d bytes long", e.KeyId, ed25519.PublicKeySize)
+		}
+		if !ed25519.Verify(key, e.signingInput(), e.Signature) {
+			return nil, ErrInvalidSignature
+		}
+	case SignatureHS256:
+		key, ok := k.HMACKeys[e.KeyId]
+		if !ok {
+			return nil, UnknownKeyError{KeyId: e.KeyId}
+		}
+		if !hmac.Equal(hmacSHA256(key, e.signingInput()), e.Signature) {
+			return nil, ErrInvalidSignature
+		}
+	default:
+		return nil, fmt.Errorf("unsupported vuforia metadata signature algorithm %q", e.Algorithm)
+	}
+
+	switch e.Encryption {
+	case "":
+		return e.Payload, nil
+	case EncryptionAESGCM:
+		key, ok := k.EncryptionKeys[e.EncryptionKeyId]
+		if !ok {
+			return nil, UnknownKeyError{KeyId: e.EncryptionKeyId}
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(e.Nonce) != gcm.NonceSize() {
+			return nil, fmt.Errorf("vuforia metadata nonce must be %d bytes long", gcm.NonceSize())
+		}
+		return gcm.Open(nil, e.Nonce, e.Payload, []byte(e.EncryptionKeyId))
+	default:
+		return nil, fmt.Errorf("unsupported vuforia metadata encryption %q", e.Encryption)
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,121 @@
+package vuforia_test
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestMetadataEnvelope(t *testing.T) {
+	payload := []byte(`{"experience":"vermeer"}`)
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	aesKey := []byte("0123456789abcdef0123456789abcdef")
+
+	keyring := vuforia.MetadataKeyring{
+		Ed25519Keys:    map[string]ed25519.PublicKey{"ed-2024": public},
+		HMACKeys:       map[string][]byte{"hmac-old": []byte("old"), "hmac-new": []byte("new")},
+		EncryptionKeys: map[string][]byte{"aes-1": aesKey},
+	}
+
+	t.Run("Ed25519", func(t *testing.T) {
+		metadata, err := vuforia.MetadataSealer{KeyId: "ed-2024", Ed25519Key: private}.Seal(payload)
+		require.NoError(t, err)
+
+		opened, err := keyring.Open(metadata)
+		require.NoError(t, err)
+		require.Equal(t, payload, opened)
+	})
+
+	t.Run("Encrypted", func(t *testing.T) {
+		metadata, err := vuforia.MetadataSealer{
+			KeyId: "hmac-new", HMACKey: []byte("new"),
+			EncryptionKeyId: "aes-1", EncryptionKey: aesKey,
+			Options: vuforia.MetadataOptions{Compress: true},
+		}.Seal(payload)
+		require.NoError(t, err)
+
+		raw, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "vermeer")
+
+		opened, err := keyring.Open(metadata)
+		require.NoError(t, err)
+		require.Equal(t, payload, opened)
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		for _, key := range []string{"old", "new"} {
+			metadata, err := vuforia.MetadataSealer{KeyId: "hmac-" + key, HMACKey: []byte(key)}.Seal(payload)
+			require.NoError(t, err)
+
+			opened, err := keyring.Open(metadata)
+			require.NoError(t, err)
+			require.Equal(t, payload, opened)
+		}
+
+		metadata, err := vuforia.MetadataSealer{KeyId: "hmac-next", HMACKey: []byte("next")}.Seal(payload)
+		require.NoError(t, err)
+		_, err = keyring.Open(metadata)
+		require.Equal(t, vuforia.UnknownKeyError{KeyId: "hmac-next"}, err)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		metadata, err := vuforia.MetadataSealer{KeyId: "ed-2024", Ed25519Key: private}.Seal(payload)
+		require.NoError(t, err)
+
+		raw, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		var e map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &e))
+		e["payload"] = base64.StdEncoding.EncodeToString([]byte(`{"experience":"spoofed"}`))
+		tampered, err := json.Marshal(e)
+		require.NoError(t, err)
+
+		_, err = keyring.Open(base64.StdEncoding.EncodeToString(tampered))
+		require.ErrorIs(t, err, vuforia.ErrInvalidSignature)
+
+		// A forged HMAC envelope reusing the ID of the Ed25519 key must not verify
+		forged, err := vuforia.MetadataSealer{KeyId: "ed-2024", HMACKey: []byte("guess")}.Seal(payload)
+		require.NoError(t, err)
+		_, err = keyring.Open(forged)
+		require.Equal(t, vuforia.UnknownKeyError{KeyId: "ed-2024"}, err)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		_, err := vuforia.MetadataSealer{KeyId: "none"}.Seal(payload)
+		require.Error(t, err)
+		_, err = vuforia.MetadataSealer{KeyId: "short", Ed25519Key: private[:16]}.Seal(payload)
+		require.Error(t, err)
+
+		metadata, err := vuforia.MetadataSealer{KeyId: "ed-2024", Ed25519Key: private}.Seal(payload)
+		require.NoError(t, err)
+		short := vuforia.MetadataKeyring{Ed25519Keys: map[string]ed25519.PublicKey{"ed-2024": public[:16]}}
+		_, err = short.Open(metadata)
+		require.Error(t, err)
+	})
+
+	t.Run("Nonce", func(t *testing.T) {
+		// A correctly signed envelope whose nonce has the wrong length
+		nonce, ciphertext := []byte("short"), []byte("ciphertext")
+		enc := base64.RawURLEncoding
+		input := "1.HS256.hmac-new.AES-GCM.aes-1." + enc.EncodeToString(nonce) + "." + enc.EncodeToString(ciphertext)
+		mac := hmac.New(sha256.New, []byte("new"))
+		mac.Write([]byte(input))
+		data, err := json.Marshal(map[string]interface{}{
+			"v": 1, "alg": "HS256", "kid": "hmac-new", "enc": "AES-GCM", "ekid": "aes-1",
+			"nonce": nonce, "payload": ciphertext, "sig": mac.Sum(nil),
+		})
+		require.NoError(t, err)
+
+		_, err = keyring.Open(base64.StdEncoding.EncodeToString(data))
+		require.Error(t, err)
+		require.NotErrorIs(t, err, vuforia.ErrInvalidSignature)
+	})
+}
@@ -0,0 +1,162 @@
+package vuforia
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when there is no blob with the key
+var ErrBlobNotFound = errors.New("vuforia metadata blob not found")
+
+// ErrMetadataHashMismatch is returned when an offloaded payload does not match the hash of its pointer
+var ErrMetadataHashMismatch = errors.New("vuforia offloaded metadata does not match its hash")
+
+// BlobStore stores the offloaded metadata payloads
+type BlobStore interface {
+	// Put stores the data under the key
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the data stored under the key, or ErrBlobNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// metadataRefMagic prefixes the pointers to offloaded metadata, followed by the version of the format
+var metadataRefMagic = []byte("VMR")
+
+// Versions of the format of the metadata following metadataRefMagic
+const (
+	// metadataRefInline escapes inline payloads starting with metadataRefMagic
+	metadataRefInline = 0
+	// metadataRefVersion is the version of the format of the pointers to offloaded metadata
+	metadataRefVersion = 1
+)
+
+// metadataRef is written to the target metadata in place of an offloaded payload
+type metadataRef struct {
+	// Key of the payload in the BlobStore, derived from its SHA-256 hash
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// MetadataOffloader stores metadata payloads in a BlobStore and writes a pointer to them in the target metadata instead,
+// allowing payloads larger than MaxMetadataSize
+type MetadataOffloader struct {
+	// Store holds the payloads
+	Store BlobStore
+	// InlineLimit is the size up to which payloads are kept in the target metadata (Optional)
+	// Defaults to keeping the payloads that fit in the target metadata; a negative limit offloads every payload.
+	InlineLimit int
+	// Options are used to encode the inline payloads and the pointers (Optional)
+	Options MetadataOptions
+}
+
+// Encode stores the payload and returns the pointer to it, encoded for PostTargetRequest.Metadata or UpdateTargetRequest.Metadata
+func (o MetadataOffloader) Encode(ctx context.Context, payload []byte) (string, error) {
+	if o.InlineLimit == 0 || len(payload) <= o.InlineLimit {
+		inline := payload
+		if bytes.HasPrefix(payload, metadataRefMagic) {
+			inline = append(append(append([]byte(nil), metadataRefMagic...), metadataRefInline), payload...)
+		}
+		metadata, err := EncodeRawMetadata(inline, o.Options)
+		if o.InlineLimit != 0 || !errors.Is(err, ErrMetadataTooLarge) {
+			return metadata, err
+		}
+	}
+
+	sum := sha256.Sum256(payload)
+	ref := metadataRef{Key: "sha256-" + hex.EncodeToString(sum[:]), Size: len(payload)}
+	if err := o.Store.Put(ctx, ref.Key, payload); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return "", err
+	}
+	return EncodeRawMetadata(append(append(append([]byte(nil), metadataRefMagic...), metadataRefVersion), data...), o.Options)
+}
+
+// Resolve returns the payload of the metadata, such as returned by Cloud Recognition queries,
+// fetching and verifying it if it was offloaded
+func (o MetadataOffloader) Resolve(ctx context.Context, metadata string) ([]byte, error) {
+	data, err := DecodeRawMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, metadataRefMagic) || len(data) == len(metadataRefMagic) {
+		return data, nil
+	}
+
+	switch version := data[len(metadataRefMagic)]; version {
+	case metadataRefInline:
+		return data[len(metadataRefMagic)+1:], nil
+	case metadataRefVersion:
+	default:
+		return nil, fmt.Errorf("unsupported vuforia metadata pointer version %d", version)
+	}
+
+	var ref metadataRef
+	if err := json.Unmarshal(data[len(metadataRefMagic)+1:], &ref); err != nil {
+		return nil, err
+	}
+
+	payload, err := o.Store.Get(ctx, ref.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(payload)
+	if len(payload) != ref.Size || ref.Key != "sha256-"+hex.EncodeToString(sum[:]) {
+		return nil, ErrMetadataHashMismatch
+	}
+	return payload, nil
+}
+
+// FileBlobStore is a BlobStore keeping every blob as a file in a directory
+type FileBlobStore struct {
+	dir string
+}
+
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+func (s *FileBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (s *FileBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+func (s *FileBlobStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
@@ -0,0 +1,88 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestMetadataOffloader(t *testing.T) {
+	dir := t.TempDir()
+	store, err := vuforia.NewFileBlobStore(dir)
+	require.NoError(t, err)
+	offloader := vuforia.MetadataOffloader{Store: store, InlineLimit: 16}
+	ctx := context.Background()
+
+	t.Run("Offloaded", func(t *testing.T) {
+		payload := bytes.Repeat([]byte("configuration"), vuforia.MaxMetadataSize/4)
+		metadata, err := offloader.Encode(ctx, payload)
+		require.NoError(t, err)
+		require.Less(t, len(metadata), 256)
+
+		resolved, err := offloader.Resolve(ctx, metadata)
+		require.NoError(t, err)
+		require.Equal(t, payload, resolved)
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, files[0].Name()), []byte("tampered"), 0o644))
+		_, err = offloader.Resolve(ctx, metadata)
+		require.ErrorIs(t, err, vuforia.ErrMetadataHashMismatch)
+	})
+
+	t.Run("Inline", func(t *testing.T) {
+		metadata, err := offloader.Encode(ctx, []byte("small"))
+		require.NoError(t, err)
+
+		decoded, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		require.Equal(t, "small", string(decoded))
+
+		resolved, err := offloader.Resolve(ctx, metadata)
+		require.NoError(t, err)
+		require.Equal(t, "small", string(resolved))
+	})
+
+	t.Run("MagicPrefix", func(t *testing.T) {
+		metadata, err := offloader.Encode(ctx, []byte("VMR\x01{}"))
+		require.NoError(t, err)
+
+		resolved, err := offloader.Resolve(ctx, metadata)
+		require.NoError(t, err)
+		require.Equal(t, "VMR\x01{}", string(resolved))
+	})
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		offloader := vuforia.MetadataOffloader{Store: store}
+		metadata, err := offloader.Encode(ctx, []byte("small"))
+		require.NoError(t, err)
+		decoded, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		require.Equal(t, "small", string(decoded), "payloads fitting in the target metadata are kept inline")
+
+		payload := bytes.Repeat([]byte("x"), vuforia.MaxMetadataSize)
+		metadata, err = offloader.Encode(ctx, payload)
+		require.NoError(t, err)
+		require.Less(t, len(metadata), 256)
+		resolved, err := offloader.Resolve(ctx, metadata)
+		require.NoError(t, err)
+		require.Equal(t, payload, resolved)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		metadata, err := vuforia.MetadataOffloader{Store: store, InlineLimit: -1}.Encode(ctx, []byte("lost payload"))
+		require.NoError(t, err)
+
+		other, err := vuforia.NewFileBlobStore(t.TempDir())
+		require.NoError(t, err)
+		_, err = vuforia.MetadataOffloader{Store: other}.Resolve(ctx, metadata)
+		require.ErrorIs(t, err, vuforia.ErrBlobNotFound)
+	})
+}
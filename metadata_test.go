@@ -0,0 +1,101 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestMetadata(t *testing.T) {
+	type artwork struct {
+		Title  string `json:"title"`
+		Artist string `json:"artist"`
+	}
+	v := artwork{Title: "Girl with a Pearl Earring", Artist: "Johannes Vermeer"}
+
+	t.Run("JSON", func(t *testing.T) {
+		metadata, err := vuforia.EncodeMetadata(v, vuforia.MetadataOptions{})
+		require.NoError(t, err)
+
+		data, err := base64.StdEncoding.DecodeString(metadata)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"title":"Girl with a Pearl Earring","artist":"Johannes Vermeer"}`, string(data))
+
+		var decoded artwork
+		require.NoError(t, vuforia.DecodeMetadata(metadata, &decoded))
+		require.Equal(t, v, decoded)
+	})
+
+	t.Run("Compressed", func(t *testing.T) {
+		raw := bytes.Repeat([]byte("metadata"), 1000)
+		metadata, err := vuforia.EncodeRawMetadata(raw, vuforia.MetadataOptions{Compress: true})
+		require.NoError(t, err)
+		require.Less(t, len(metadata), len(raw)/10)
+
+		decoded, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		require.Equal(t, raw, decoded)
+	})
+
+	t.Run("Unpadded", func(t *testing.T) {
+		decoded, err := vuforia.DecodeRawMetadata(base64.RawStdEncoding.EncodeToString([]byte("License")))
+		require.NoError(t, err)
+		require.Equal(t, "License", string(decoded))
+
+		_, err = vuforia.DecodeRawMetadata("not base64!")
+		require.Error(t, err)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		raw := []byte(strings.Repeat("x", vuforia.MaxMetadataSize))
+		_, err := vuforia.EncodeRawMetadata(raw, vuforia.MetadataOptions{})
+		require.ErrorIs(t, err, vuforia.ErrMetadataTooLarge)
+
+		metadata, err := vuforia.EncodeRawMetadata(raw, vuforia.MetadataOptions{Compress: true})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(metadata), vuforia.MaxMetadataSize)
+
+		// Data larger than the metadata is accepted when it compresses under the limit
+		raw = bytes.Repeat([]byte("x"), 4*vuforia.MaxMetadataSize)
+		metadata, err = vuforia.EncodeRawMetadata(raw, vuforia.MetadataOptions{Compress: true})
+		require.NoError(t, err)
+		decoded, err := vuforia.DecodeRawMetadata(metadata)
+		require.NoError(t, err)
+		require.Equal(t, raw, decoded)
+
+		_, err = vuforia.EncodeRawMetadata(make([]byte, vuforia.MaxDecompressedMetadataSize+1), vuforia.MetadataOptions{Compress: true})
+		require.ErrorIs(t, err, vuforia.ErrMetadataTooLarge)
+	})
+
+	t.Run("MagicPrefix", func(t *testing.T) {
+		for _, raw := range []string{"VMD", "VMD\x01data", "VMD\x00data"} {
+			metadata, err := vuforia.EncodeRawMetadata([]byte(raw), vuforia.MetadataOptions{})
+			require.NoError(t, err)
+			decoded, err := vuforia.DecodeRawMetadata(metadata)
+			require.NoError(t, err)
+			require.Equal(t, raw, string(decoded))
+		}
+	})
+
+	t.Run("DecompressionBomb", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.WriteString("VMD\x01")
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(make([]byte, vuforia.MaxDecompressedMetadataSize+1))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		_, err = vuforia.DecodeRawMetadata(base64.StdEncoding.EncodeToString(buf.Bytes()))
+		require.ErrorIs(t, err, vuforia.ErrMetadataTooLarge)
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		_, err := vuforia.DecodeRawMetadata(base64.StdEncoding.EncodeToString([]byte("VMD\x02data")))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,148 @@
+package vuforia
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics collects Prometheus metrics about the requests sent to the Vuforia Web Services API
+// and the state of the database. It implements prometheus.Collector.
+type Metrics struct {
+	requests    *prometheus.CounterVec
+	latency     *prometheus.HistogramVec
+	resultCodes *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	limiterWait *prometheus.HistogramVec
+
+	targetsUsed  *prometheus.GaugeVec
+	targetQuota  *prometheus.GaugeVec
+	requestsUsed *prometheus.GaugeVec
+	requestQuota *prometheus.GaugeVec
+	failedImages *prometheus.GaugeVec
+}
+
+// NewMetrics creates the metrics with the given namespace prepended to their names
+func NewMetrics(namespace string) *Metrics {
+	opLabels := []string{"operation"}
+	dbLabels := []string{"database"}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "requests_total",
+			Help: "Number of HTTP requests sent to the Vuforia Web Services API.",
+		}, opLabels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "operation_duration_seconds",
+			Help:    "Duration of the operations, including retries and rate limiting.",
+			Buckets: prometheus.DefBuckets,
+		}, opLabels),
+		resultCodes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "results_total",
+			Help: "Number of operations completed per VWS API result code.",
+		}, []string{"operation", "result_code"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "retries_total",
+			Help: "Number of requests retried after a server error.",
+		}, opLabels),
+		limiterWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "rate_limiter_wait_seconds",
+			Help:    "Time spent waiting for the rate limiter before sending a request.",
+			Buckets: prometheus.DefBuckets,
+		}, opLabels),
+		targetsUsed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "database_targets",
+			Help: "Number of targets in the database.",
+		}, dbLabels),
+		targetQuota: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "database_target_quota",
+			Help: "Maximum number of targets in the database.",
+		}, dbLabels),
+		requestsUsed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "database_requests",
+			Help: "Number of API requests made against the database in the current period.",
+		}, dbLabels),
+		requestQuota: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "database_request_quota",
+			Help: "Maximum number of API requests against the database in the current period.",
+		}, dbLabels),
+		failedImages: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "vuforia", Name: "database_failed_images",
+			Help: "Number of targets in the database whose image failed processing.",
+		}, dbLabels),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.requests, m.latency, m.resultCodes, m.retries, m.limiterWait,
+		m.targetsUsed, m.targetQuota, m.requestsUsed, m.requestQuota, m.failedImages,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// ObserveDatabaseSummary updates the database gauges from the summary.
+// Clients configured with the metrics call it on every DatabaseSummary.
+func (m *Metrics) ObserveDatabaseSummary(summary *DatabaseSummaryResponse) {
+	if m == nil || summary == nil {
+		return
+	}
+
+	used := summary.ActiveImages + summary.InactiveImages + summary.FailedImages + summary.ProcessingImages
+	m.targetsUsed.WithLabelValues(summary.Name).Set(float64(used))
+	m.targetQuota.WithLabelValues(summary.Name).Set(float64(summary.TargetQuota))
+	m.requestsUsed.WithLabelValues(summary.Name).Set(float64(summary.RequestUsage))
+	m.requestQuota.WithLabelValues(summary.Name).Set(float64(summary.RequestQuota))
+	m.failedImages.WithLabelValues(summary.Name).Set(float64(summary.FailedImages))
+}
+
+func (m *Metrics) observeAttempt(op string, attempt int) {
+	if m == nil {
+		return
+	}
+
+	m.requests.WithLabelValues(op).Inc()
+	if attempt > 1 {
+		m.retries.WithLabelValues(op).Inc()
+	}
+}
+
+func (m *Metrics) observeLimiterWait(op string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.limiterWait.WithLabelValues(op).Observe(d.Seconds())
+}
+
+func (m *Metrics) observeOperation(op string, d time.Duration, res result, err error) {
+	if m == nil {
+		return
+	}
+
+	m.latency.WithLabelValues(op).Observe(d.Seconds())
+
+	code := res.ResultCode
+	if code == "" && err != nil {
+		var se ServerError
+		if errors.As(err, &se) {
+			code = "ServerError"
+		} else {
+			code = "Error"
+		}
+	}
+	m.resultCodes.WithLabelValues(op, code).Inc()
+}
@@ -0,0 +1,83 @@
+package vuforia_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+type sleepLimiter time.Duration
+
+func (l sleepLimiter) Wait(ctx context.Context) error {
+	time.Sleep(time.Duration(l))
+	return nil
+}
+
+func TestMetrics(t *testing.T) {
+	f := newFakeVWS(t)
+	metrics := vuforia.NewMetrics("test")
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics))
+
+	client := f.client(t, vuforia.ClientConfig{
+		Metrics:     metrics,
+		MaxRetries:  1,
+		RateLimiter: sleepLimiter(time.Millisecond),
+	})
+	f.addTarget("failed", 0, "failed")
+
+	f.fail(http.StatusInternalServerError, "")
+	_, err := client.DatabaseSummary(context.Background())
+	require.NoError(t, err)
+
+	_, err = client.GetTarget(context.Background(), &vuforia.GetTargetRequest{TargetId: "unknown"})
+	require.Error(t, err)
+
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP test_vuforia_requests_total Number of HTTP requests sent to the Vuforia Web Services API.
+# TYPE test_vuforia_requests_total counter
+test_vuforia_requests_total{operation="DatabaseSummary"} 2
+test_vuforia_requests_total{operation="GetTarget"} 1
+# HELP test_vuforia_retries_total Number of requests retried after a server error.
+# TYPE test_vuforia_retries_total counter
+test_vuforia_retries_total{operation="DatabaseSummary"} 1
+# HELP test_vuforia_results_total Number of operations completed per VWS API result code.
+# TYPE test_vuforia_results_total counter
+test_vuforia_results_total{operation="DatabaseSummary",result_code="Success"} 1
+test_vuforia_results_total{operation="GetTarget",result_code="UnknownTarget"} 1
+# HELP test_vuforia_database_targets Number of targets in the database.
+# TYPE test_vuforia_database_targets gauge
+test_vuforia_database_targets{database="fake"} 1
+# HELP test_vuforia_database_failed_images Number of targets in the database whose image failed processing.
+# TYPE test_vuforia_database_failed_images gauge
+test_vuforia_database_failed_images{database="fake"} 1
+# HELP test_vuforia_database_target_quota Maximum number of targets in the database.
+# TYPE test_vuforia_database_target_quota gauge
+test_vuforia_database_target_quota{database="fake"} 1000
+# HELP test_vuforia_database_request_quota Maximum number of API requests against the database in the current period.
+# TYPE test_vuforia_database_request_quota gauge
+test_vuforia_database_request_quota{database="fake"} 100000
+# HELP test_vuforia_database_requests Number of API requests made against the database in the current period.
+# TYPE test_vuforia_database_requests gauge
+test_vuforia_database_requests{database="fake"} 2
+`),
+		"test_vuforia_requests_total",
+		"test_vuforia_retries_total",
+		"test_vuforia_results_total",
+		"test_vuforia_database_targets",
+		"test_vuforia_database_failed_images",
+		"test_vuforia_database_target_quota",
+		"test_vuforia_database_request_quota",
+		"test_vuforia_database_requests",
+	))
+
+	require.Equal(t, 2, testutil.CollectAndCount(metrics, "test_vuforia_operation_duration_seconds"))
+	require.Equal(t, 2, testutil.CollectAndCount(metrics, "test_vuforia_rate_limiter_wait_seconds"))
+}
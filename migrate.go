@@ -0,0 +1,222 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// TargetMapping maps a target of the source database to its copy in the destination database
+type TargetMapping struct {
+	SourceId      string `json:"source_id"`
+	DestinationId string `json:"destination_id"`
+	Name          string `json:"name"`
+	// ImageHash is the hash of the image last migrated, see HashImage
+	ImageHash  string    `json:"image_hash"`
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// ReadMapping reads a mapping file written by Migrate; it is empty if the file does not exist
+func ReadMapping(path string) ([]TargetMapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []TargetMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+type MigrateConfig struct {
+	// Source is the client of the database to copy the targets from
+	Source Client
+	// Destination is the client of the database to copy the targets to.
+	// Set its WaitProcessing to update targets that are still processing.
+	Destination Client
+	// Selector selects the targets of the source database to migrate
+	Selector TargetSelector
+	// Images provides the images of the source targets
+	Images ImageSource
+	// SourceIndex provides the metadata of the source targets; metadata is not migrated when not set (Optional)
+	SourceIndex *Index
+	// MappingPath is the mapping file between the source and destination IDs; it is read to find the
+	// targets migrated before and updated with every migrated target
+	MappingPath string
+}
+
+// MigrateReport maps the source IDs of the migrated targets to their destination IDs
+type MigrateReport struct {
+	// Created are the targets added to the destination
+	Created map[string]string
+	// Updated are the targets that already existed in the destination, by mapping or by name
+	Updated map[string]string
+	// Failed are the targets that could not be migrated
+	Failed map[string]error
+}
+
+// Migrate copies the selected targets from the source database to the destination database, such as to
+// promote curated targets from staging to production. The names, widths, active flags, metadata and images
+// are preserved; targets migrated before, or with the same name, are updated instead of created, without
+// sending the image again if it did not change.
+func Migrate(ctx context.Context, cfg MigrateConfig) (*MigrateReport, error) {
+	switch {
+	case cfg.Source == nil:
+		return nil, errors.New("migrate Source must be set")
+	case cfg.Destination == nil:
+		return nil, errors.New("migrate Destination must be set")
+	case cfg.Images == nil:
+		return nil, errors.New("migrate Images must be set")
+	case cfg.MappingPath == "":
+		return nil, errors.New("migrate MappingPath must be set")
+	}
+
+	if err := cfg.Selector.validate(); err != nil {
+		return nil, fmt.Errorf("migrate %w", err)
+	}
+
+	existing, err := ReadMapping(cfg.MappingPath)
+	if err != nil {
+		return nil, err
+	}
+	mappings := map[string]TargetMapping{}
+	for _, m := range existing {
+		mappings[m.SourceId] = m
+	}
+
+	ids, err := cfg.Selector.candidates(ctx, cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := targetsByName(ctx, cfg.Destination)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, id := range destination {
+		known[id] = true
+	}
+
+	report := &MigrateReport{Created: map[string]string{}, Updated: map[string]string{}, Failed: map[string]error{}}
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		m, created, err := migrateTarget(ctx, cfg, id, mappings[id], destination, known)
+		switch {
+		case err != nil:
+			report.Failed[id] = err
+			continue
+		case m == nil:
+			// Not selected
+			continue
+		case created:
+			report.Created[id] = m.DestinationId
+		default:
+			report.Updated[id] = m.DestinationId
+		}
+
+		// The mapping is saved right away so that an interrupted migration does not create the target again
+		mappings[id] = *m
+		if err := writeMapping(cfg.MappingPath, mappings); err != nil {
+			return report, err
+		}
+	}
+	return report, ctx.Err()
+}
+
+// writeMapping replaces the mapping file with the mappings ordered by source ID
+func writeMapping(path string, mappings map[string]TargetMapping) error {
+	result := make([]TargetMapping, 0, len(mappings))
+	for _, m := range mappings {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SourceId < result[j].SourceId
+	})
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// migrateTarget creates or updates the copy of a source target; it returns a nil mapping if the target is not selected
+func migrateTarget(ctx context.Context, cfg MigrateConfig, id string, previous TargetMapping, destination map[string]string, known map[string]bool) (*TargetMapping, bool, error) {
+	summary, err := cfg.Source.TargetSummary(ctx, &TargetSummaryRequest{TargetId: id})
+	if err != nil {
+		return nil, false, err
+	}
+	if !cfg.Selector.matches(summary) {
+		return nil, false, nil
+	}
+
+	source, err := cfg.Source.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+	if err != nil {
+		return nil, false, err
+	}
+	rec := source.TargetRecord
+
+	data, err := cfg.Images.Image(ctx, rec)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading image of target %s: %w", id, err)
+	}
+	image := base64.StdEncoding.EncodeToString(data)
+	hash, err := HashImage(image)
+	if err != nil {
+		return nil, false, err
+	}
+	m := &TargetMapping{SourceId: id, Name: rec.Name, ImageHash: hash, MigratedAt: time.Now().UTC()}
+
+	var metadata *string
+	if cfg.SourceIndex != nil {
+		if r, ok := cfg.SourceIndex.Get(id); ok {
+			metadata = r.Metadata
+		}
+	}
+
+	// The previous copy is only reused if it still exists
+	m.DestinationId = previous.DestinationId
+	if !known[m.DestinationId] {
+		m.DestinationId = destination[rec.Name]
+	}
+
+	active := rec.Active
+	if m.DestinationId == "" {
+		resp, err := cfg.Destination.PostTarget(ctx, &PostTargetRequest{
+			Name:     rec.Name,
+			Width:    rec.Width,
+			Image:    image,
+			Active:   &active,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		m.DestinationId = resp.TargetId
+		return m, true, nil
+	}
+
+	width := rec.Width
+	input := &UpdateTargetRequest{TargetId: m.DestinationId, Name: &rec.Name, Width: &width, Active: &active, Metadata: metadata}
+	if m.DestinationId != previous.DestinationId || m.ImageHash != previous.ImageHash {
+		input.Image = &image
+	}
+	if _, err := cfg.Destination.UpdateTarget(ctx, input); err != nil {
+		return nil, false, err
+	}
+	return m, false, nil
+}
@@ -0,0 +1,99 @@
+package vuforia_test
+
+import (
+	"context"
+	"encoding/base64"
+	"path"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestMigrate(t *testing.T) {
+	staging, production := newFakeVWS(t), newFakeVWS(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	index, err := vuforia.OpenIndex(filepath.Join(dir, "index.json"))
+	require.NoError(t, err)
+	poster := staging.addTarget("poster", 0, "success")
+	flyer := staging.addTarget("flyer", 0, "success")
+	staging.update(flyer, func(t *fakeTarget) { t.active, t.width = false, 3 })
+	staging.addTarget("draft", 0, "success")
+	metadata := base64.StdEncoding.EncodeToString([]byte("metadata"))
+	require.NoError(t, index.Put(vuforia.IndexRecord{TargetId: poster, Name: "poster", Metadata: &metadata}))
+
+	// The flyer was uploaded to production by hand
+	existing := production.addTarget("flyer", 0, "success")
+
+	images := map[string]string{"poster": "poster image", "flyer": "flyer image"}
+	cfg := vuforia.MigrateConfig{
+		Source:      staging.client(t, vuforia.ClientConfig{}),
+		Destination: production.client(t, vuforia.ClientConfig{}),
+		Selector:    vuforia.TargetSelector{NameRegexp: regexp.MustCompile(`^(poster|flyer)$`)},
+		Images: vuforia.ImageSourceFunc(func(ctx context.Context, target vuforia.TargetRecord) ([]byte, error) {
+			return []byte(images[target.Name]), nil
+		}),
+		SourceIndex: index,
+		MappingPath: filepath.Join(dir, "mapping.json"),
+	}
+
+	invalid := cfg
+	invalid.Selector = vuforia.TargetSelector{NameGlob: "[abc"}
+	_, err = vuforia.Migrate(ctx, invalid)
+	require.ErrorIs(t, err, path.ErrBadPattern)
+
+	report, err := vuforia.Migrate(ctx, cfg)
+	require.NoError(t, err)
+	require.Empty(t, report.Failed)
+	require.Len(t, report.Created, 1)
+	require.Equal(t, map[string]string{flyer: existing}, report.Updated)
+
+	copied := production.target(report.Created[poster])
+	require.Equal(t, "poster", copied.name)
+	require.Equal(t, metadata, copied.metadata)
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("poster image")), copied.image)
+	require.False(t, production.target(existing).active)
+	require.Equal(t, 3.0, production.target(existing).width)
+
+	mappings, err := vuforia.ReadMapping(cfg.MappingPath)
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+
+	t.Run("Interrupted", func(t *testing.T) {
+		interrupted := cfg
+		interrupted.Destination = newFakeVWS(t).client(t, vuforia.ClientConfig{})
+		interrupted.MappingPath = filepath.Join(t.TempDir(), "mapping.json")
+		calls := 0
+		interrupted.Images = vuforia.ImageSourceFunc(func(ctx context.Context, target vuforia.TargetRecord) ([]byte, error) {
+			if calls++; calls == 2 {
+				panic("crash")
+			}
+			return []byte(images[target.Name]), nil
+		})
+
+		require.Panics(t, func() { _, _ = vuforia.Migrate(ctx, interrupted) })
+		mappings, err := vuforia.ReadMapping(interrupted.MappingPath)
+		require.NoError(t, err)
+		require.Len(t, mappings, 1, "the mapping of the target migrated before the crash is saved")
+	})
+
+	t.Run("Again", func(t *testing.T) {
+		staging.update(poster, func(t *fakeTarget) { t.width = 7 })
+		production.update(report.Created[poster], func(t *fakeTarget) { t.status = "success" })
+		production.update(existing, func(t *fakeTarget) { t.status = "success" })
+
+		again, err := vuforia.Migrate(ctx, cfg)
+		require.NoError(t, err)
+		require.Empty(t, again.Failed)
+		require.Empty(t, again.Created)
+		require.Equal(t, map[string]string{poster: report.Created[poster], flyer: existing}, again.Updated)
+
+		copied := production.target(report.Created[poster])
+		require.Equal(t, 7.0, copied.width)
+		require.Equal(t, "success", copied.status, "the unchanged image is not sent again")
+	})
+}
@@ -0,0 +1,353 @@
+package vuforia
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyPermission allows a caller of the Proxy a kind of operation
+type ProxyPermission string
+
+const (
+	// PermissionRead allows GetTarget, TargetSummary, DatabaseSummary, ListTargets and Duplicates
+	PermissionRead ProxyPermission = "read"
+	// PermissionWrite allows PostTarget and UpdateTarget
+	PermissionWrite ProxyPermission = "write"
+	// PermissionDelete allows DeleteTarget
+	PermissionDelete ProxyPermission = "delete"
+)
+
+// Result codes of the requests rejected by the Proxy
+const (
+	ResultCodeUnauthorized     = "Unauthorized"
+	ResultCodePermissionDenied = "PermissionDenied"
+	ResultCodeRateLimited      = "RateLimited"
+)
+
+// proxyMaxBody is the maximum size of the request bodies, leaving room for base64 encoded images
+const proxyMaxBody = 8 << 20
+
+// ProxyCaller is a service allowed to call the Proxy
+type ProxyCaller struct {
+	// Name identifies the caller in the audit log
+	Name string `json:"name"`
+	// TokenSHA256 is the hex encoded SHA-256 hash of the bearer token of the caller
+	TokenSHA256 string `json:"token_sha256"`
+	// Permissions are the kinds of operations the caller may perform
+	Permissions []ProxyPermission `json:"permissions"`
+	// RateLimit is the number of requests per second the caller may send; unlimited when 0 (Optional)
+	RateLimit float64 `json:"rate_limit,omitempty"`
+	// Burst is the number of requests the caller may send at once (Optional)
+	// Defaults to the RateLimit rounded up.
+	Burst int `json:"burst,omitempty"`
+}
+
+// ProxyAuditEntry records a call to the Proxy
+type ProxyAuditEntry struct {
+	Time          time.Time     `json:"time"`
+	Caller        string        `json:"caller,omitempty"`
+	Operation     string        `json:"operation"`
+	TargetId      string        `json:"target_id,omitempty"`
+	StatusCode    int           `json:"status_code"`
+	ResultCode    string        `json:"result_code"`
+	TransactionId string        `json:"transaction_id,omitempty"`
+	Duration      time.Duration `json:"duration"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type ProxyConfig struct {
+	// Client forwards the requests to VWS with the credentials of the database
+	Client Client
+	// Callers are the services allowed to call the proxy
+	Callers []ProxyCaller
+	// Audit is called with every request, authenticated or not (Optional)
+	Audit func(ProxyAuditEntry)
+	// Clock is used to rate limit the callers (Optional)
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// Proxy is an http.Handler exposing the Vuforia Web Services API to callers authenticated by their own
+// bearer tokens, so that they do not need the credentials of the database. It serves the paths of the
+// VWS API: /targets, /targets/{id}, /summary, /summary/{id} and /duplicates/{id}.
+type Proxy struct {
+	cfg     ProxyConfig
+	callers map[string]*proxyCaller
+}
+
+type proxyCaller struct {
+	ProxyCaller
+	token []byte
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket of the caller if there is one
+func (c *proxyCaller) allow(now time.Time) bool {
+	if c.RateLimit == 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.last.IsZero() {
+		c.tokens = math.Min(float64(c.Burst), c.tokens+now.Sub(c.last).Seconds()*c.RateLimit)
+	}
+	c.last = now
+	if c.tokens < 1 {
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+func (c *proxyCaller) can(p ProxyPermission) bool {
+	for _, permission := range c.Permissions {
+		if permission == p {
+			return true
+		}
+	}
+	return false
+}
+
+func NewProxy(cfg ProxyConfig) (*Proxy, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("proxy Client must be set")
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+
+	p := &Proxy{cfg: cfg, callers: map[string]*proxyCaller{}}
+	for _, c := range cfg.Callers {
+		if _, ok := p.callers[c.Name]; ok || c.Name == "" {
+			return nil, errors.New("proxy caller names must be set and unique")
+		}
+		token, err := hex.DecodeString(c.TokenSHA256)
+		if err != nil || len(token) != sha256.Size {
+			return nil, errors.New("proxy caller " + c.Name + " TokenSHA256 must be a hex encoded SHA-256 hash")
+		}
+		if c.RateLimit < 0 || c.Burst < 0 {
+			return nil, errors.New("proxy caller " + c.Name + " RateLimit and Burst must not be negative")
+		}
+		for _, permission := range c.Permissions {
+			switch permission {
+			case PermissionRead, PermissionWrite, PermissionDelete:
+			default:
+				return nil, errors.New("proxy caller " + c.Name + " has unknown permission " + string(permission))
+			}
+		}
+		if c.Burst == 0 {
+			c.Burst = int(math.Ceil(c.RateLimit))
+		}
+		p.callers[c.Name] = &proxyCaller{ProxyCaller: c, token: token, tokens: float64(c.Burst)}
+	}
+	return p, nil
+}
+
+// proxyTargetId matches the target IDs, so that the path of the request forwarded to VWS cannot be steered
+// to another endpoint
+var proxyTargetId = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// proxyRoute is an operation of the VWS API
+type proxyRoute struct {
+	operation  string
+	permission ProxyPermission
+	targetId   string
+}
+
+func route(method, path string) (proxyRoute, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var id string
+	if len(parts) == 2 {
+		id = parts[1]
+	} else if len(parts) > 2 {
+		return proxyRoute{}, false
+	}
+	if len(parts) == 2 && !proxyTargetId.MatchString(id) {
+		return proxyRoute{}, false
+	}
+
+	switch {
+	case parts[0] == "targets" && id == "" && method == http.MethodPost:
+		return proxyRoute{"PostTarget", PermissionWrite, ""}, true
+	case parts[0] == "targets" && id == "" && method == http.MethodGet:
+		return proxyRoute{"ListTargets", PermissionRead, ""}, true
+	case parts[0] == "targets" && id != "" && method == http.MethodGet:
+		return proxyRoute{"GetTarget", PermissionRead, id}, true
+	case parts[0] == "targets" && id != "" && method == http.MethodPut:
+		return proxyRoute{"UpdateTarget", PermissionWrite, id}, true
+	case parts[0] == "targets" && id != "" && method == http.MethodDelete:
+		return proxyRoute{"DeleteTarget", PermissionDelete, id}, true
+	case parts[0] == "summary" && id == "" && method == http.MethodGet:
+		return proxyRoute{"DatabaseSummary", PermissionRead, ""}, true
+	case parts[0] == "summary" && id != "" && method == http.MethodGet:
+		return proxyRoute{"TargetSummary", PermissionRead, id}, true
+	case parts[0] == "duplicates" && id != "" && method == http.MethodGet:
+		return proxyRoute{"Duplicates", PermissionRead, id}, true
+	}
+	return proxyRoute{}, false
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	entry := ProxyAuditEntry{Time: start.UTC(), Operation: r.Method + " " + r.URL.Path}
+	defer func() {
+		entry.Duration = time.Since(start)
+		if p.cfg.Audit != nil {
+			p.cfg.Audit(entry)
+		}
+	}()
+
+	reply := func(status int, v interface{}, err error) {
+		entry.StatusCode = status
+		if err != nil {
+			entry.Error = err.Error()
+		}
+
+		data, _ := json.Marshal(v)
+		var res result
+		_ = json.Unmarshal(data, &res)
+		entry.ResultCode, entry.TransactionId = res.ResultCode, res.TransactionId
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	}
+	fail := func(status int, code string, err error) {
+		reply(status, APIError{ResultCode: code}, err)
+	}
+
+	caller := p.authenticate(r)
+	if caller == nil {
+		fail(http.StatusUnauthorized, ResultCodeUnauthorized, nil)
+		return
+	}
+	entry.Caller = caller.Name
+
+	rt, ok := route(r.Method, r.URL.Path)
+	if !ok {
+		fail(http.StatusNotFound, "Fail", nil)
+		return
+	}
+	entry.Operation, entry.TargetId = rt.operation, rt.targetId
+
+	if !caller.can(rt.permission) {
+		fail(http.StatusForbidden, ResultCodePermissionDenied, nil)
+		return
+	}
+
+	if !caller.allow(p.cfg.Clock.Now()) {
+		fail(http.StatusTooManyRequests, ResultCodeRateLimited, nil)
+		return
+	}
+
+	status, v, err := p.forward(r, rt)
+	var ae APIError
+	switch {
+	case err == nil:
+		reply(status, v, nil)
+	case status == http.StatusBadRequest:
+		fail(http.StatusBadRequest, "Fail", err)
+	case errors.As(err, &ae) && ae.StatusCode != 0:
+		reply(ae.StatusCode, ae, err)
+	case errors.As(err, &ae):
+		reply(apiErrorStatus(ae.ResultCode), ae, err)
+	default:
+		fail(http.StatusBadGateway, "Fail", err)
+	}
+}
+
+// forward performs the operation with the client and returns the status and the body of the response.
+// The status is http.StatusBadRequest when the request body cannot be decoded.
+func (p *Proxy) forward(r *http.Request, rt proxyRoute) (int, interface{}, error) {
+	ctx, client := r.Context(), p.cfg.Client
+	decode := func(v interface{}) error {
+		return json.NewDecoder(io.LimitReader(r.Body, proxyMaxBody)).Decode(v)
+	}
+
+	switch rt.operation {
+	case "PostTarget":
+		var input PostTargetRequest
+		if err := decode(&input); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		v, err := client.PostTarget(ctx, &input)
+		return http.StatusCreated, v, err
+	case "UpdateTarget":
+		var input UpdateTargetRequest
+		if err := decode(&input); err != nil {
+			return http.StatusBadRequest, nil, err
+		}
+		input.TargetId = rt.targetId
+		v, err := client.UpdateTarget(ctx, &input)
+		return http.StatusOK, v, err
+	case "DeleteTarget":
+		v, err := client.DeleteTarget(ctx, &DeleteTargetRequest{TargetId: rt.targetId})
+		return http.StatusOK, v, err
+	case "GetTarget":
+		v, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: rt.targetId})
+		return http.StatusOK, v, err
+	case "ListTargets":
+		v, err := listTargets(ctx, client)
+		return http.StatusOK, v, err
+	case "TargetSummary":
+		v, err := client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: rt.targetId})
+		return http.StatusOK, v, err
+	case "DatabaseSummary":
+		v, err := client.DatabaseSummary(ctx)
+		return http.StatusOK, v, err
+	default:
+		v, err := findDuplicates(ctx, client, &DuplicatesRequest{TargetId: rt.targetId})
+		return http.StatusOK, v, err
+	}
+}
+
+// authenticate returns the caller whose token is the bearer token of the request
+func (p *Proxy) authenticate(r *http.Request) *proxyCaller {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	var found *proxyCaller
+	for _, c := range p.callers {
+		if subtle.ConstantTimeCompare(sum[:], c.token) == 1 {
+			found = c
+		}
+	}
+	return found
+}
+
+// apiErrorStatus returns the HTTP status VWS responds with for the result code, for the API errors
+// raised by the middlewares rather than VWS
+func apiErrorStatus(resultCode string) int {
+	switch resultCode {
+	case "UnknownTarget":
+		return http.StatusNotFound
+	case "AuthenticationFailure":
+		return http.StatusUnauthorized
+	case "RequestQuotaReached":
+		return http.StatusTooManyRequests
+	case "BadImage", "ImageTooLarge", "MetadataTooLarge", "DateRangeError":
+		return http.StatusUnprocessableEntity
+	case "Fail":
+		return http.StatusBadRequest
+	default:
+		return http.StatusForbidden
+	}
+}
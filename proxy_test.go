@@ -0,0 +1,143 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestProxy(t *testing.T) {
+	f := newFakeVWS(t)
+	id := f.addTarget("existing", 0, "success")
+
+	var mu sync.Mutex
+	var audit []vuforia.ProxyAuditEntry
+	clock := &fakeClock{now: time.Now()}
+	_, err := vuforia.NewProxy(vuforia.ProxyConfig{
+		Client:  f.client(t, vuforia.ClientConfig{}),
+		Callers: []vuforia.ProxyCaller{{Name: "bad", TokenSHA256: "token"}},
+	})
+	require.Error(t, err)
+	for _, caller := range []vuforia.ProxyCaller{
+		{Name: "negative", TokenSHA256: hashToken("token"), RateLimit: -1},
+		{Name: "negative", TokenSHA256: hashToken("token"), RateLimit: 1, Burst: -1},
+		{Name: "typo", TokenSHA256: hashToken("token"), Permissions: []vuforia.ProxyPermission{"wirte"}},
+	} {
+		_, err = vuforia.NewProxy(vuforia.ProxyConfig{Client: f.client(t, vuforia.ClientConfig{}), Callers: []vuforia.ProxyCaller{caller}})
+		require.Error(t, err, caller.Name)
+	}
+
+	proxy, err := vuforia.NewProxy(vuforia.ProxyConfig{
+		Client: f.client(t, vuforia.ClientConfig{}),
+		Callers: []vuforia.ProxyCaller{
+			{Name: "dashboard", TokenSHA256: hashToken("read-token"), Permissions: []vuforia.ProxyPermission{vuforia.PermissionRead}, RateLimit: 1, Burst: 2},
+			{Name: "cms", TokenSHA256: hashToken("write-token"), Permissions: []vuforia.ProxyPermission{vuforia.PermissionRead, vuforia.PermissionWrite}},
+		},
+		Audit: func(e vuforia.ProxyAuditEntry) {
+			mu.Lock()
+			defer mu.Unlock()
+			audit = append(audit, e)
+		},
+		Clock: clock,
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	call := func(token, method, path string, body interface{}) (int, map[string]interface{}) {
+		var reqBody bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &reqBody)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var v map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+		return resp.StatusCode, v
+	}
+
+	status, v := call("", http.MethodGet, "/targets/"+id, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.Equal(t, vuforia.ResultCodeUnauthorized, v["result_code"])
+	status, _ = call("wrong-token", http.MethodGet, "/targets/"+id, nil)
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	status, v = call("read-token", http.MethodGet, "/targets/"+id, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "existing", v["target_record"].(map[string]interface{})["name"])
+
+	status, v = call("read-token", http.MethodDelete, "/targets/"+id, nil)
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, vuforia.ResultCodePermissionDenied, v["result_code"])
+	require.NotNil(t, f.target(id))
+
+	// The burst of the dashboard is spent
+	status, v = call("read-token", http.MethodGet, "/summary", nil)
+	require.Equal(t, http.StatusOK, status)
+	status, v = call("read-token", http.MethodGet, "/summary", nil)
+	require.Equal(t, http.StatusTooManyRequests, status)
+	require.Equal(t, vuforia.ResultCodeRateLimited, v["result_code"])
+	clock.After(time.Second)
+	status, _ = call("read-token", http.MethodGet, "/summary", nil)
+	require.Equal(t, http.StatusOK, status)
+
+	image := base64.StdEncoding.EncodeToString([]byte("image"))
+	status, v = call("write-token", http.MethodPost, "/targets", vuforia.PostTargetRequest{Name: "new", Width: 1, Image: image})
+	require.Equal(t, http.StatusCreated, status)
+	require.Equal(t, "new", f.target(v["target_id"].(string)).name)
+
+	status, v = call("write-token", http.MethodPost, "/targets", vuforia.PostTargetRequest{Name: "new", Width: 1, Image: image})
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "TargetNameExist", v["result_code"])
+
+	status, _ = call("write-token", http.MethodPut, "/targets/"+id, "not a request")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = call("write-token", http.MethodGet, "/targets/"+id+"/extra", nil)
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = call("write-token", http.MethodGet, "/targets/..", nil)
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = call("write-token", http.MethodGet, "/summary/not-a-target-id", nil)
+	require.Equal(t, http.StatusNotFound, status)
+
+	// The status VWS responded with is passed through
+	f.fail(http.StatusUnprocessableEntity, "TargetStatusNotSuccess")
+	status, v = call("write-token", http.MethodGet, "/targets/"+id, nil)
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	require.Equal(t, "TargetStatusNotSuccess", v["result_code"])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, audit, 14)
+	require.Empty(t, audit[0].Caller)
+	require.Equal(t, "dashboard", audit[2].Caller)
+	require.Equal(t, "GetTarget", audit[2].Operation)
+	require.Equal(t, id, audit[2].TargetId)
+	require.NotEmpty(t, audit[2].TransactionId)
+	require.Equal(t, "cms", audit[7].Caller)
+	require.Equal(t, "PostTarget", audit[7].Operation)
+	require.Equal(t, "TargetCreated", audit[7].ResultCode)
+	require.NotEmpty(t, audit[8].TransactionId, "failed calls are audited with their transaction")
+	require.Equal(t, "TargetNameExist", audit[8].ResultCode)
+}
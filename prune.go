@@ -0,0 +1,244 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// PruneRule returns why the target should be pruned, or an empty string to keep it
+type PruneRule func(now time.Time, target *TargetSummaryResponse) string
+
+// FailedFor prunes the targets that failed processing and were uploaded more than the duration ago
+func FailedFor(d time.Duration) PruneRule {
+	return func(now time.Time, target *TargetSummaryResponse) string {
+		if target.Status == StatusFailed && !target.UploadDate.IsZero() && now.Sub(target.UploadDate.Time) > d {
+			return fmt.Sprintf("failed processing, uploaded on %s", target.UploadDate)
+		}
+		return ""
+	}
+}
+
+// ZeroRecos prunes the targets not recognized in the current or previous month
+func ZeroRecos() PruneRule {
+	return func(now time.Time, target *TargetSummaryResponse) string {
+		if target.Status == StatusSuccess && target.CurrentMonthRecos == 0 && target.PreviousMonthRecos == 0 {
+			return "not recognized in the current or previous month"
+		}
+		return ""
+	}
+}
+
+// InactiveUploadedBefore prunes the inactive targets uploaded before the date
+func InactiveUploadedBefore(date time.Time) PruneRule {
+	return func(now time.Time, target *TargetSummaryResponse) string {
+		if !target.Active && !target.UploadDate.IsZero() && target.UploadDate.Before(date) {
+			return fmt.Sprintf("inactive, uploaded on %s", target.UploadDate)
+		}
+		return ""
+	}
+}
+
+// RatingBelow prunes the processed targets rated under the rating
+func RatingBelow(rating TrackingRating) PruneRule {
+	return func(now time.Time, target *TargetSummaryResponse) string {
+		if target.Status == StatusSuccess && target.TrackingRating.Rated() && target.TrackingRating < rating {
+			return fmt.Sprintf("tracking rating %d under %d", target.TrackingRating, rating)
+		}
+		return ""
+	}
+}
+
+// AllOf prunes the targets matching all the rules, with their reasons joined
+func AllOf(rules ...PruneRule) PruneRule {
+	return func(now time.Time, target *TargetSummaryResponse) string {
+		var reason string
+		for _, rule := range rules {
+			r := rule(now, target)
+			if r == "" {
+				return ""
+			}
+			if reason != "" {
+				reason += " and "
+			}
+			reason += r
+		}
+		return reason
+	}
+}
+
+type PruneConfig struct {
+	// Client is used to retrieve and delete the targets
+	Client Client
+	// Rules select the targets to prune; a target is pruned if any rule matches
+	Rules []PruneRule
+	// TargetIds are the targets to evaluate; all the targets of the database when empty (Optional)
+	TargetIds []string
+	// Index holds the records of the targets; pruned targets are removed from it (Optional)
+	Index *Index
+	// ArchivePath is a file every pruned target is appended to, as a JSON line, before it is deleted (Optional)
+	ArchivePath string
+	// Concurrency is the number of requests sent at once (Optional)
+	// Defaults to 4.
+	Concurrency int
+	// Clock tells the time the rules are evaluated at (Optional)
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// PruneCandidate is a target selected by the rules
+type PruneCandidate struct {
+	TargetId string
+	Summary  *TargetSummaryResponse
+	// Reasons are given by the rules matching the target
+	Reasons []string
+}
+
+// PrunePlan lists the targets to prune; nothing is deleted until it is applied
+type PrunePlan struct {
+	cfg        PruneConfig
+	Candidates []PruneCandidate
+}
+
+// ArchivedTarget is the line appended to the archive for every pruned target
+type ArchivedTarget struct {
+	TargetId string                 `json:"target_id"`
+	Summary  *TargetSummaryResponse `json:"summary"`
+	// Record is the record of the target in the index, if any
+	Record     *IndexRecord `json:"record,omitempty"`
+	Reasons    []string     `json:"reasons"`
+	ArchivedAt time.Time    `json:"archived_at"`
+}
+
+// PlanPrune evaluates the rules against the summary of every target
+func PlanPrune(ctx context.Context, cfg PruneConfig) (*PrunePlan, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("prune Client must be set")
+	}
+
+	if len(cfg.Rules) == 0 {
+		return nil, errors.New("prune Rules must be set")
+	}
+
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+
+	if cfg.Concurrency < 0 {
+		return nil, errors.New("prune Concurrency must not be negative")
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+
+	ids := cfg.TargetIds
+	if len(ids) == 0 {
+		list, err := listTargets(ctx, cfg.Client)
+		if err != nil {
+			return nil, err
+		}
+		ids = list.Results
+	}
+
+	summaries := make([]*TargetSummaryResponse, len(ids))
+	errs := make([]error, len(ids))
+	forEach(ctx, len(ids), cfg.Concurrency, func(i int) {
+		summaries[i], errs[i] = cfg.Client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: ids[i]})
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := cfg.Clock.Now()
+	plan := &PrunePlan{cfg: cfg}
+	for i, s := range summaries {
+		if err := errs[i]; err != nil {
+			var ae APIError
+			if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+				continue
+			}
+			return nil, err
+		}
+
+		c := PruneCandidate{TargetId: ids[i], Summary: s}
+		for _, rule := range cfg.Rules {
+			if reason := rule(now, s); reason != "" {
+				c.Reasons = append(c.Reasons, reason)
+			}
+		}
+		if len(c.Reasons) > 0 {
+			plan.Candidates = append(plan.Candidates, c)
+		}
+	}
+	return plan, nil
+}
+
+// Apply archives, if configured, and deletes the candidates concurrently.
+// Failures are reported per target; the error is only set when the context is done.
+func (p *PrunePlan) Apply(ctx context.Context) (*BulkReport, error) {
+	var mu sync.Mutex
+	report := &BulkReport{Action: BulkDelete, Results: make([]BulkResult, len(p.Candidates))}
+	forEach(ctx, len(p.Candidates), p.cfg.Concurrency, func(i int) {
+		c := p.Candidates[i]
+		res := BulkResult{TargetId: c.TargetId, Name: c.Summary.TargetName}
+		defer func() { report.Results[i] = res }()
+
+		if p.cfg.ArchivePath != "" {
+			mu.Lock()
+			res.Err = p.archive(c)
+			mu.Unlock()
+			if res.Err != nil {
+				return
+			}
+		}
+
+		if _, res.Err = p.cfg.Client.DeleteTarget(ctx, &DeleteTargetRequest{TargetId: c.TargetId}); res.Err != nil {
+			return
+		}
+
+		if p.cfg.Index != nil {
+			if err := p.cfg.Index.Delete(c.TargetId); err != nil {
+				res.Err = IndexError{Err: err}
+			}
+		}
+	})
+
+	for i := range report.Results {
+		if report.Results[i].TargetId == "" {
+			// Not attempted before the context was done
+			c := p.Candidates[i]
+			report.Results[i] = BulkResult{TargetId: c.TargetId, Name: c.Summary.TargetName, Err: ctx.Err()}
+		}
+	}
+	return report, ctx.Err()
+}
+
+// archive appends the candidate to the archive; the lock must be held
+func (p *PrunePlan) archive(c PruneCandidate) error {
+	a := ArchivedTarget{TargetId: c.TargetId, Summary: c.Summary, Reasons: c.Reasons, ArchivedAt: p.cfg.Clock.Now().UTC()}
+	if p.cfg.Index != nil {
+		if r, ok := p.cfg.Index.Get(c.TargetId); ok {
+			a.Record = &r
+		}
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(p.cfg.ArchivePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
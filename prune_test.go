@@ -0,0 +1,89 @@
+package vuforia_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestPrune(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	failed := f.addTarget("failed", 0, "failed")
+	blurry := f.addTarget("blurry", 0, "success")
+	f.update(blurry, func(t *fakeTarget) { t.rating = 1 })
+	inactive := f.addTarget("inactive", 0, "success")
+	f.update(inactive, func(t *fakeTarget) { t.active = false })
+	good := f.addTarget("good", 0, "success")
+
+	index, err := vuforia.OpenIndex(filepath.Join(dir, "index.json"))
+	require.NoError(t, err)
+	require.NoError(t, index.Put(vuforia.IndexRecord{TargetId: inactive, Name: "inactive", Tags: []string{"2020"}}))
+
+	_, err = vuforia.PlanPrune(ctx, vuforia.PruneConfig{Client: client})
+	require.Error(t, err)
+	_, err = vuforia.PlanPrune(ctx, vuforia.PruneConfig{Client: client, Rules: []vuforia.PruneRule{vuforia.ZeroRecos()}, Concurrency: -1})
+	require.Error(t, err)
+
+	archive := filepath.Join(dir, "archive.jsonl")
+	// The fake targets are uploaded on 2021-05-01
+	clock := &fakeClock{now: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)}
+	plan, err := vuforia.PlanPrune(ctx, vuforia.PruneConfig{
+		Client: client,
+		Rules: []vuforia.PruneRule{
+			vuforia.FailedFor(30 * 24 * time.Hour),
+			vuforia.RatingBelow(3),
+			vuforia.AllOf(vuforia.InactiveUploadedBefore(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)), vuforia.ZeroRecos()),
+		},
+		Index:       index,
+		ArchivePath: archive,
+		Clock:       clock,
+	})
+	require.NoError(t, err)
+
+	reasons := map[string][]string{}
+	for _, c := range plan.Candidates {
+		reasons[c.TargetId] = c.Reasons
+	}
+	require.Equal(t, map[string][]string{
+		failed:   {"failed processing, uploaded on 2021-05-01"},
+		blurry:   {"tracking rating 1 under 3"},
+		inactive: {"inactive, uploaded on 2021-05-01 and not recognized in the current or previous month"},
+	}, reasons)
+	require.NotNil(t, f.target(failed), "planning deletes nothing")
+
+	report, err := plan.Apply(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 3)
+	require.Empty(t, report.Failed())
+
+	list, err := client.(vuforia.Lister).ListTargets(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{good}, list.Results)
+	require.Empty(t, index.All())
+
+	file, err := os.Open(archive)
+	require.NoError(t, err)
+	defer file.Close()
+	archived := map[string]vuforia.ArchivedTarget{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var a vuforia.ArchivedTarget
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &a))
+		archived[a.TargetId] = a
+	}
+	require.Len(t, archived, 3)
+	require.Equal(t, []string{"2020"}, archived[inactive].Record.Tags)
+	require.Nil(t, archived[failed].Record)
+	require.Equal(t, "blurry", archived[blurry].Summary.TargetName)
+}
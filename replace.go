@@ -0,0 +1,174 @@
+package vuforia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ReplaceImageOptions struct {
+	// MinTrackingRating is the tracking rating the new image must reach (Optional)
+	// Defaults to the tracking rating of the target being replaced.
+	MinTrackingRating TrackingRating
+	// AllowDuplicates allows replacing the image with one similar to the image of another target (Optional)
+	AllowDuplicates bool
+	// Wait configures the polling of the staging target until it is processed (Optional)
+	Wait WaitOptions
+	// CleanupTimeout bounds the wait for the staging target to be processed and deleted,
+	// which happens even once the context is done (Optional)
+	// Defaults to 10 minutes.
+	CleanupTimeout time.Duration
+}
+
+// ReplaceImageReport describes the outcome of ReplaceImage
+type ReplaceImageReport struct {
+	TargetId string
+	// StagingTargetId is the ID of the inactive target the new image was validated with
+	StagingTargetId string
+	// Replaced indicates the image of the target was updated
+	Replaced bool
+	// Reason the replacement was aborted; empty if replaced
+	Reason string
+	// PreviousRating is the tracking rating of the target before the replacement
+	PreviousRating TrackingRating
+	// StagingRating is the tracking rating of the new image
+	StagingRating TrackingRating
+	// Duplicates are the targets whose image is similar to the new image
+	Duplicates []string
+	// StagingDeleted indicates the staging target was deleted
+	StagingDeleted bool
+}
+
+// ReplaceImage replaces the image of a target only once the new image is known to rate well.
+// The new image is first uploaded as an inactive staging target; the image of the target is updated
+// only if the staging target is processed successfully, rates at least MinTrackingRating, and has no
+// duplicates unless allowed. The staging target is deleted in any case.
+// Checking the duplicates requires the client to implement DuplicateFinder, unless AllowDuplicates is set.
+//
+// An aborted replacement is not an error: the reason is given by the report. An error is returned
+// along with the report if a request fails, including the deletion of the staging target.
+func ReplaceImage(ctx context.Context, client Client, targetId, image string, opts ReplaceImageOptions) (report *ReplaceImageReport, err error) {
+	ctx, span := tracerFor(client).Start(ctx, "ReplaceImage")
+	span.SetAttributes(attrTargetId.String(targetId))
+	defer func() {
+		if report != nil {
+			span.SetAttributes(attribute.Bool("vuforia.replaced", report.Replaced))
+		}
+		endSpan(span, err)
+	}()
+
+	finder, _ := client.(DuplicateFinder)
+	if finder == nil && !opts.AllowDuplicates {
+		return nil, fmt.Errorf("vuforia client %T does not implement DuplicateFinder", client)
+	}
+
+	if opts.CleanupTimeout == 0 {
+		opts.CleanupTimeout = 10 * time.Minute
+	}
+
+	current, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: targetId})
+	if err != nil {
+		return nil, err
+	}
+
+	report = &ReplaceImageReport{TargetId: targetId, PreviousRating: current.TargetRecord.TrackingRating}
+	if opts.MinTrackingRating == 0 {
+		opts.MinTrackingRating = current.TargetRecord.TrackingRating
+		// Any image would rate higher than an unrated target
+		if !opts.MinTrackingRating.Rated() {
+			opts.MinTrackingRating = 0
+		}
+	}
+
+	inactive := false
+	staging, err := client.PostTarget(ctx, &PostTargetRequest{
+		Name:   stagingName(current.TargetRecord.Name),
+		Width:  current.TargetRecord.Width,
+		Image:  image,
+		Active: &inactive,
+	})
+	if err != nil {
+		return report, err
+	}
+	report.StagingTargetId = staging.TargetId
+
+	var stagingProcessed bool
+	defer func() {
+		// The staging target counts against the target quota, so it is deleted even when the replacement is cancelled
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), opts.CleanupTimeout)
+		defer cancel()
+		if derr := deleteStaging(cleanupCtx, client, staging.TargetId, stagingProcessed, opts.Wait); derr != nil {
+			if err == nil {
+				err = fmt.Errorf("deleting staging target %s: %w", staging.TargetId, derr)
+			}
+			return
+		}
+		report.StagingDeleted = true
+	}()
+
+	processed, err := WaitUntilProcessedWithOptions(ctx, client, staging.TargetId, opts.Wait)
+	if errors.Is(err, ErrProcessingFailed) {
+		stagingProcessed = true
+		report.Reason = "the new image failed processing"
+		return report, nil
+	}
+	if err != nil {
+		return report, err
+	}
+	stagingProcessed = true
+	report.StagingRating = processed.TargetRecord.TrackingRating
+
+	if finder != nil {
+		duplicates, err := finder.Duplicates(ctx, &DuplicatesRequest{TargetId: staging.TargetId})
+		if err != nil {
+			return report, err
+		}
+		for _, id := range duplicates.SimilarTargets {
+			// The new image is expected to resemble the image it replaces
+			if id != targetId {
+				report.Duplicates = append(report.Duplicates, id)
+			}
+		}
+	}
+
+	switch {
+	case report.StagingRating < opts.MinTrackingRating:
+		report.Reason = fmt.Sprintf("the new image rates %d, under %d", report.StagingRating, opts.MinTrackingRating)
+		return report, nil
+	case len(report.Duplicates) > 0 && !opts.AllowDuplicates:
+		report.Reason = fmt.Sprintf("the new image is similar to %d other targets", len(report.Duplicates))
+		return report, nil
+	}
+
+	if _, err := client.UpdateTarget(ctx, &UpdateTargetRequest{TargetId: targetId, Image: &image}); err != nil {
+		return report, err
+	}
+	report.Replaced = true
+	return report, nil
+}
+
+// deleteStaging deletes the staging target, once processed since targets cannot be deleted while processing
+func deleteStaging(ctx context.Context, client Client, targetId string, processed bool, wait WaitOptions) error {
+	if !processed {
+		// The wait is bounded by the context instead
+		wait.MaxWait, wait.Progress = 0, nil
+		if _, err := WaitUntilProcessedWithOptions(ctx, client, targetId, wait); err != nil && !errors.Is(err, ErrProcessingFailed) {
+			return err
+		}
+	}
+	_, err := client.DeleteTarget(ctx, &DeleteTargetRequest{TargetId: targetId})
+	return err
+}
+
+// stagingName returns a name, unique within the database, for the staging target of a target
+func stagingName(name string) string {
+	suffix := fmt.Sprintf(".staging-%d", time.Now().UnixNano())
+	// Target names are limited to 64 characters
+	if runes := []rune(name); len(runes)+len(suffix) > 64 {
+		name = string(runes[:64-len(suffix)])
+	}
+	return name + suffix
+}
@@ -0,0 +1,114 @@
+package vuforia_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestReplaceImage(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	ctx := context.Background()
+	opts := vuforia.ReplaceImageOptions{Wait: vuforia.WaitOptions{InitialInterval: time.Millisecond}}
+
+	id := f.addTarget("poster", 0, "success")
+	f.update(id, func(t *fakeTarget) { t.image, t.rating = "old", 4 })
+	other := f.addTarget("other", 0, "success")
+	f.update(other, func(t *fakeTarget) { t.image = "taken" })
+	f.imageRatings["blurry"] = 2
+	f.failingImages["corrupt"] = true
+
+	t.Run("LowRating", func(t *testing.T) {
+		report, err := vuforia.ReplaceImage(ctx, client, id, "blurry", opts)
+		require.NoError(t, err)
+		require.False(t, report.Replaced)
+		require.Equal(t, "the new image rates 2, under 4", report.Reason)
+		require.Equal(t, vuforia.TrackingRating(4), report.PreviousRating)
+		require.Equal(t, vuforia.TrackingRating(2), report.StagingRating)
+		require.True(t, report.StagingDeleted)
+		require.Nil(t, f.target(report.StagingTargetId))
+		require.Equal(t, "old", f.target(id).image)
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		report, err := vuforia.ReplaceImage(ctx, client, id, "taken", opts)
+		require.NoError(t, err)
+		require.False(t, report.Replaced)
+		require.Equal(t, []string{other}, report.Duplicates)
+		require.Equal(t, "old", f.target(id).image)
+	})
+
+	t.Run("ProcessingFailed", func(t *testing.T) {
+		report, err := vuforia.ReplaceImage(ctx, client, id, "corrupt", opts)
+		require.NoError(t, err)
+		require.False(t, report.Replaced)
+		require.Equal(t, "the new image failed processing", report.Reason)
+		require.True(t, report.StagingDeleted)
+	})
+
+	t.Run("WaitTimeout", func(t *testing.T) {
+		f.imagePolls["slow"] = 3
+		opts := vuforia.ReplaceImageOptions{Wait: vuforia.WaitOptions{
+			InitialInterval: time.Second,
+			MaxWait:         time.Second,
+			Clock:           &fakeClock{now: time.Now()},
+		}}
+		report, err := vuforia.ReplaceImage(ctx, client, id, "slow", opts)
+		require.ErrorIs(t, err, vuforia.ErrWaitTimeout)
+		require.True(t, report.StagingDeleted, "the staging target is deleted once processed")
+		require.Nil(t, f.target(report.StagingTargetId))
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		opts := opts
+		opts.Wait.Progress = func(*vuforia.GetTargetResponse) { cancel() }
+		report, err := vuforia.ReplaceImage(ctx, client, id, "slow", opts)
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, report.StagingDeleted)
+		require.Nil(t, f.target(report.StagingTargetId))
+		require.Equal(t, "old", f.target(id).image)
+	})
+
+	t.Run("NoDuplicateFinder", func(t *testing.T) {
+		client := struct{ vuforia.Client }{client}
+		_, err := vuforia.ReplaceImage(ctx, client, id, "sharp", opts)
+		require.Error(t, err)
+
+		opts := opts
+		opts.AllowDuplicates = true
+		opts.MinTrackingRating = 5
+		report, err := vuforia.ReplaceImage(ctx, client, id, "taken", opts)
+		require.NoError(t, err)
+		require.True(t, report.Replaced)
+		require.Empty(t, report.Duplicates)
+		f.update(id, func(t *fakeTarget) { t.image, t.status, t.rating = "old", "success", 4 })
+	})
+
+	t.Run("LongName", func(t *testing.T) {
+		// The staging name is truncated within the accents
+		long := f.addTarget("affiches-"+strings.Repeat("é", 56), 0, "success")
+		report, err := vuforia.ReplaceImage(ctx, client, long, "sharp", opts)
+		require.NoError(t, err)
+		require.True(t, report.Replaced)
+		f.remove(long)
+	})
+
+	t.Run("Replaced", func(t *testing.T) {
+		report, err := vuforia.ReplaceImage(ctx, client, id, "sharp", opts)
+		require.NoError(t, err)
+		require.True(t, report.Replaced)
+		require.Empty(t, report.Reason)
+		require.Equal(t, vuforia.TrackingRating(5), report.StagingRating)
+		require.True(t, report.StagingDeleted)
+		require.Equal(t, "sharp", f.target(id).image)
+		list, err := client.(vuforia.Lister).ListTargets(ctx)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{id, other}, list.Results, "staging targets are deleted")
+	})
+}
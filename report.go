@@ -0,0 +1,240 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type ReportConfig struct {
+	// Client is used to retrieve the summaries
+	Client Client
+	// TargetIds are the targets to report on; all the targets of the database when empty (Optional)
+	TargetIds []string
+	// Filter selects the targets to include in the report (Optional)
+	Filter func(*TargetSummaryResponse) bool
+	// LowRatingThreshold is the tracking rating under which a target is reported as low rated (Optional)
+	// Defaults to 3.
+	LowRatingThreshold TrackingRating
+}
+
+// Report is the recognition analytics of a set of targets
+type Report struct {
+	// GeneratedAt is the time the report was built
+	GeneratedAt time.Time `json:"generated_at"`
+	// Database is the name of the database the targets reside in
+	Database string `json:"database"`
+	// LowRatingThreshold is the tracking rating under which a target is reported as low rated
+	LowRatingThreshold TrackingRating `json:"low_rating_threshold"`
+	// Targets are ordered by rank
+	Targets []ReportTarget `json:"targets"`
+}
+
+type ReportTarget struct {
+	// Rank of the target by recognitions in the current month, then in total
+	Rank           int            `json:"rank"`
+	TargetId       string         `json:"target_id"`
+	Name           string         `json:"name"`
+	Status         TargetStatus   `json:"status"`
+	Active         bool           `json:"active"`
+	TrackingRating TrackingRating `json:"tracking_rating"`
+	UploadDate     Date           `json:"upload_date"`
+	TotalRecos     int            `json:"total_recos"`
+	// CurrentMonthRecos is the count of recognitions in the current month
+	CurrentMonthRecos int `json:"current_month_recos"`
+	// PreviousMonthRecos is the count of recognitions in the previous month
+	PreviousMonthRecos int `json:"previous_month_recos"`
+	// MonthOverMonth is the relative change of recognitions from the previous month to the current month;
+	// nil if there were no recognitions in the previous month
+	MonthOverMonth *float64 `json:"month_over_month,omitempty"`
+	// ZeroRecos indicates the target was not recognized in the current or previous month
+	ZeroRecos bool `json:"zero_recos"`
+	// LowRating indicates the target is rated under the threshold
+	LowRating bool `json:"low_rating"`
+}
+
+// BuildReport retrieves the summaries of the targets and computes their analytics
+func BuildReport(ctx context.Context, cfg ReportConfig) (*Report, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("report Client must be set")
+	}
+
+	if cfg.LowRatingThreshold == 0 {
+		cfg.LowRatingThreshold = 3
+	}
+
+	ids := cfg.TargetIds
+	if len(ids) == 0 {
+		list, err := listTargets(ctx, cfg.Client)
+		if err != nil {
+			return nil, err
+		}
+		ids = list.Results
+	}
+
+	report := &Report{GeneratedAt: time.Now().UTC(), LowRatingThreshold: cfg.LowRatingThreshold}
+	for _, id := range ids {
+		s, err := cfg.Client.TargetSummary(ctx, &TargetSummaryRequest{TargetId: id})
+		if err != nil {
+			return nil, err
+		}
+
+		report.Database = s.DatabaseName
+		if cfg.Filter != nil && !cfg.Filter(s) {
+			continue
+		}
+
+		t := ReportTarget{
+			TargetId:           id,
+			Name:               s.TargetName,
+			Status:             s.Status,
+			Active:             s.Active,
+			TrackingRating:     s.TrackingRating,
+			UploadDate:         s.UploadDate,
+			TotalRecos:         s.TotalRecos,
+			CurrentMonthRecos:  s.CurrentMonthRecos,
+			PreviousMonthRecos: s.PreviousMonthRecos,
+			ZeroRecos:          s.CurrentMonthRecos == 0 && s.PreviousMonthRecos == 0,
+			LowRating:          s.TrackingRating.Rated() && s.TrackingRating < cfg.LowRatingThreshold,
+		}
+		if s.PreviousMonthRecos > 0 {
+			change := float64(s.CurrentMonthRecos-s.PreviousMonthRecos) / float64(s.PreviousMonthRecos)
+			t.MonthOverMonth = &change
+		}
+		report.Targets = append(report.Targets, t)
+	}
+
+	sort.SliceStable(report.Targets, func(i, j int) bool {
+		a, b := report.Targets[i], report.Targets[j]
+		if a.CurrentMonthRecos != b.CurrentMonthRecos {
+			return a.CurrentMonthRecos > b.CurrentMonthRecos
+		}
+		if a.TotalRecos != b.TotalRecos {
+			return a.TotalRecos > b.TotalRecos
+		}
+		return a.Name < b.Name
+	})
+	for i := range report.Targets {
+		report.Targets[i].Rank = i + 1
+	}
+
+	return report, nil
+}
+
+// ZeroRecos returns the targets not recognized in the current or previous month
+func (r *Report) ZeroRecos() []ReportTarget {
+	var targets []ReportTarget
+	for _, t := range r.Targets {
+		if t.ZeroRecos {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// LowRated returns the targets rated under the threshold
+func (r *Report) LowRated() []ReportTarget {
+	var targets []ReportTarget
+	for _, t := range r.Targets {
+		if t.LowRating {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes one line per target, with a header line
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"rank", "target_id", "name", "status", "active", "tracking_rating", "upload_date",
+		"total_recos", "current_month_recos", "previous_month_recos", "month_over_month", "zero_recos", "low_rating",
+	}); err != nil {
+		return err
+	}
+
+	for _, t := range r.Targets {
+		var mom string
+		if t.MonthOverMonth != nil {
+			mom = strconv.FormatFloat(*t.MonthOverMonth, 'f', 4, 64)
+		}
+		if err := cw.Write([]string{
+			strconv.Itoa(t.Rank), t.TargetId, t.Name, string(t.Status), strconv.FormatBool(t.Active),
+			strconv.Itoa(int(t.TrackingRating)), t.UploadDate.String(), strconv.Itoa(t.TotalRecos),
+			strconv.Itoa(t.CurrentMonthRecos), strconv.Itoa(t.PreviousMonthRecos), mom,
+			strconv.FormatBool(t.ZeroRecos), strconv.FormatBool(t.LowRating),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHTML writes the report as a self-contained HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v *float64) string {
+		if v == nil {
+			return "n/a"
+		}
+		return strconv.FormatFloat(*v*100, 'f', 1, 64) + "%"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Recognition report{{with .Database}} - {{.}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+.flag { color: #b00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Recognition report{{with .Database}} - {{.}}{{end}}</h1>
+<p>Generated at {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}} for {{len .Targets}} targets.</p>
+
+<h2>Targets by recognitions</h2>
+<table>
+<tr><th>Rank</th><th>Name</th><th>Target ID</th><th>Status</th><th>Active</th><th>Rating</th><th>Uploaded</th><th>Total</th><th>Current month</th><th>Previous month</th><th>Change</th></tr>
+{{range .Targets}}<tr>
+<td class="num">{{.Rank}}</td><td>{{.Name}}</td><td>{{.TargetId}}</td><td>{{.Status}}</td><td>{{.Active}}</td>
+<td class="num{{if .LowRating}} flag{{end}}">{{.TrackingRating}}</td><td>{{.UploadDate}}</td>
+<td class="num">{{.TotalRecos}}</td><td class="num">{{.CurrentMonthRecos}}</td><td class="num">{{.PreviousMonthRecos}}</td>
+<td class="num">{{percent .MonthOverMonth}}</td>
+</tr>
+{{end}}</table>
+
+<h2>Not recognized in the current or previous month</h2>
+{{with .ZeroRecos}}<ul>
+{{range .}}<li>{{.Name}} ({{.TargetId}}), uploaded {{.UploadDate}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+
+<h2>Tracking rating under {{.LowRatingThreshold}}</h2>
+{{with .LowRated}}<ul>
+{{range .}}<li>{{.Name}} ({{.TargetId}}): {{.TrackingRating}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body>
+</html>
+`))
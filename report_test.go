@@ -0,0 +1,74 @@
+package vuforia_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// summaryClient returns canned target summaries
+type summaryClient struct {
+	vuforia.Client
+	summaries map[string]*vuforia.TargetSummaryResponse
+}
+
+func (c summaryClient) ListTargets(ctx context.Context) (*vuforia.ListTargetsResponse, error) {
+	var ids []string
+	for id := range c.summaries {
+		ids = append(ids, id)
+	}
+	return &vuforia.ListTargetsResponse{ResultCode: "Success", Results: ids}, nil
+}
+
+func (c summaryClient) TargetSummary(ctx context.Context, input *vuforia.TargetSummaryRequest) (*vuforia.TargetSummaryResponse, error) {
+	return c.summaries[input.TargetId], nil
+}
+
+func TestReport(t *testing.T) {
+	client := summaryClient{summaries: map[string]*vuforia.TargetSummaryResponse{
+		"a": {DatabaseName: "db", TargetName: "popular", Status: "success", TrackingRating: 5, TotalRecos: 100, CurrentMonthRecos: 30, PreviousMonthRecos: 20},
+		"b": {DatabaseName: "db", TargetName: "dead", Status: "success", TrackingRating: 4, TotalRecos: 10},
+		"c": {DatabaseName: "db", TargetName: "blurry", Status: "success", TrackingRating: 1, TotalRecos: 5, CurrentMonthRecos: 1, PreviousMonthRecos: 4},
+		"d": {DatabaseName: "db", TargetName: "failed", Status: "failed", TrackingRating: 0},
+	}}
+
+	report, err := vuforia.BuildReport(context.Background(), vuforia.ReportConfig{
+		Client: client,
+		Filter: func(s *vuforia.TargetSummaryResponse) bool { return s.Status == "success" },
+	})
+	require.NoError(t, err)
+	require.Equal(t, "db", report.Database)
+	require.Len(t, report.Targets, 3)
+
+	popular, blurry, dead := report.Targets[0], report.Targets[1], report.Targets[2]
+	require.Equal(t, "a", popular.TargetId)
+	require.Equal(t, 1, popular.Rank)
+	require.InDelta(t, 0.5, *popular.MonthOverMonth, 1e-9)
+	require.Equal(t, "c", blurry.TargetId)
+	require.InDelta(t, -0.75, *blurry.MonthOverMonth, 1e-9)
+	require.True(t, blurry.LowRating)
+	require.Equal(t, "b", dead.TargetId)
+	require.Nil(t, dead.MonthOverMonth)
+	require.True(t, dead.ZeroRecos)
+	require.Equal(t, []vuforia.ReportTarget{dead}, report.ZeroRecos())
+	require.Equal(t, []vuforia.ReportTarget{blurry}, report.LowRated())
+
+	var csv bytes.Buffer
+	require.NoError(t, report.WriteCSV(&csv))
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, "1,a,popular,success,false,5,,100,30,20,0.5000,false,false", lines[1])
+
+	var html bytes.Buffer
+	require.NoError(t, report.WriteHTML(&html))
+	require.Contains(t, html.String(), "<li>dead (b), uploaded </li>")
+	require.Contains(t, html.String(), "-75.0%")
+
+	var js bytes.Buffer
+	require.NoError(t, report.WriteJSON(&js))
+	require.Contains(t, js.String(), `"month_over_month": 0.5`)
+}
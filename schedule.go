@@ -0,0 +1,310 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ActivationWindow is a period during which a target is active.
+// A scheduled target is inactive outside of its windows.
+type ActivationWindow struct {
+	TargetId string    `json:"target_id"`
+	Start    time.Time `json:"start"`
+	// End of the window, excluded; the window never ends when zero
+	End time.Time `json:"end,omitempty"`
+}
+
+// Contains reports whether the window is open at the time
+func (w ActivationWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && (w.End.IsZero() || t.Before(w.End))
+}
+
+// Schedule stores the activation windows of the targets in a local JSON file
+type Schedule struct {
+	path string
+
+	mu      sync.RWMutex
+	windows []ActivationWindow
+}
+
+// OpenSchedule loads the schedule from the file, which is created on the first change if it does not exist
+func OpenSchedule(path string) (*Schedule, error) {
+	s := &Schedule{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload replaces the windows with the ones in the file, such as written by another process
+func (s *Schedule) Reload() error {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var windows []ActivationWindow
+	if err := json.Unmarshal(data, &windows); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = windows
+	return nil
+}
+
+// Add adds a window to the schedule
+func (s *Schedule) Add(w ActivationWindow) error {
+	if w.TargetId == "" {
+		return errors.New("activation window TargetId must be set")
+	}
+	if !w.End.IsZero() && !w.End.After(w.Start) {
+		return errors.New("activation window must end after it starts")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = append(s.windows, w)
+	return s.save()
+}
+
+// Remove removes all the windows of the target, which is no longer scheduled
+func (s *Schedule) Remove(targetId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows := s.windows[:0]
+	for _, w := range s.windows {
+		if w.TargetId != targetId {
+			windows = append(windows, w)
+		}
+	}
+	s.windows = windows
+	return s.save()
+}
+
+// Windows returns the windows of the target ordered by start
+func (s *Schedule) Windows(targetId string) []ActivationWindow {
+	var windows []ActivationWindow
+	for _, w := range s.All() {
+		if w.TargetId == targetId {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// All returns all the windows ordered by target ID then start
+func (s *Schedule) All() []ActivationWindow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	windows := append([]ActivationWindow(nil), s.windows...)
+	sort.SliceStable(windows, func(i, j int) bool {
+		if windows[i].TargetId != windows[j].TargetId {
+			return windows[i].TargetId < windows[j].TargetId
+		}
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows
+}
+
+// ActiveAt returns whether every scheduled target should be active at the time
+func (s *Schedule) ActiveAt(t time.Time) map[string]bool {
+	active := map[string]bool{}
+	for _, w := range s.All() {
+		active[w.TargetId] = active[w.TargetId] || w.Contains(t)
+	}
+	return active
+}
+
+// NextChange returns the first start or end of a window after the time; false if there is none
+func (s *Schedule) NextChange(t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, w := range s.All() {
+		for _, b := range []time.Time{w.Start, w.End} {
+			if b.After(t) && (next.IsZero() || b.Before(next)) {
+				next = b
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// save writes the windows to the file; the lock must be held
+func (s *Schedule) save() error {
+	data, err := json.MarshalIndent(s.windows, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+type SchedulerConfig struct {
+	// Client is used to retrieve and update the targets
+	Client Client
+	// Schedule holds the windows of the targets; it is reloaded before every reconciliation
+	Schedule *Schedule
+	// Interval is the maximum time between two reconciliations, which corrects changes made by other clients (Optional)
+	// Defaults to 1 hour.
+	Interval time.Duration
+	// RetryInterval is the time before reconciling again after a target could not be updated (Optional)
+	// Defaults to 1 minute.
+	RetryInterval time.Duration
+	// OnError is called when reconciling fails (Optional)
+	OnError func(error)
+	// Clock tells the time the windows are evaluated at and waits between reconciliations (Optional)
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// ScheduleReport lists the changes made by a reconciliation
+type ScheduleReport struct {
+	// Activated are the targets activated
+	Activated []string
+	// Deactivated are the targets deactivated
+	Deactivated []string
+	// Removed are the targets no longer in the database, removed from the schedule
+	Removed []string
+	// Failed are the targets that could not be retrieved or updated, retried on the next reconciliation
+	Failed map[string]error
+}
+
+// Scheduler applies the active flag of the targets according to their activation windows
+type Scheduler struct {
+	cfg SchedulerConfig
+}
+
+func NewScheduler(cfg SchedulerConfig) (*Scheduler, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("scheduler Client must be set")
+	}
+
+	if cfg.Schedule == nil {
+		return nil, errors.New("scheduler Schedule must be set")
+	}
+
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Hour
+	}
+
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = time.Minute
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+
+	return &Scheduler{cfg: cfg}, nil
+}
+
+// Reconcile sets the active flag of every scheduled target that differs from its schedule at the current time.
+// Since the flag is compared with the one of the target rather than with the previous run, windows
+// that started or ended while the scheduler was not running are caught up.
+func (s *Scheduler) Reconcile(ctx context.Context) (*ScheduleReport, error) {
+	if err := s.cfg.Schedule.Reload(); err != nil {
+		return nil, err
+	}
+
+	report := &ScheduleReport{Failed: map[string]error{}}
+	desired := s.cfg.Schedule.ActiveAt(s.cfg.Clock.Now())
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		active := desired[id]
+		output, err := s.cfg.Client.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+		if err == nil && output.TargetRecord.Active != active {
+			_, err = s.cfg.Client.UpdateTarget(ctx, &UpdateTargetRequest{TargetId: id, Active: &active})
+			if err == nil && active {
+				report.Activated = append(report.Activated, id)
+			} else if err == nil {
+				report.Deactivated = append(report.Deactivated, id)
+			}
+		}
+
+		var ae APIError
+		switch {
+		case err == nil:
+		case errors.As(err, &ae) && ae.ResultCode == "UnknownTarget":
+			if err := s.cfg.Schedule.Remove(id); err != nil {
+				return report, err
+			}
+			report.Removed = append(report.Removed, id)
+		default:
+			report.Failed[id] = err
+		}
+	}
+
+	return report, ctx.Err()
+}
+
+// Run reconciles the targets whenever a window starts or ends, and at least every Interval, until the context is done
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		wait := s.cfg.Interval
+		report, err := s.Reconcile(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			s.onError(err)
+			wait = s.cfg.RetryInterval
+		} else if len(report.Failed) > 0 {
+			for id, err := range report.Failed {
+				s.onError(ScheduleError{TargetId: id, Err: err})
+			}
+			wait = s.cfg.RetryInterval
+		}
+
+		now := s.cfg.Clock.Now()
+		if next, ok := s.cfg.Schedule.NextChange(now); ok && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.cfg.Clock.After(wait):
+		}
+	}
+}
+
+func (s *Scheduler) onError(err error) {
+	if s.cfg.OnError != nil {
+		s.cfg.OnError(err)
+	}
+}
+
+// ScheduleError is reported to OnError when the active flag of a target could not be reconciled
+type ScheduleError struct {
+	TargetId string
+	Err      error
+}
+
+func (e ScheduleError) Error() string {
+	return fmt.Sprintf("reconciling target %s failed: %v", e.TargetId, e.Err)
+}
+
+func (e ScheduleError) Unwrap() error {
+	return e.Err
+}
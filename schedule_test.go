@@ -0,0 +1,101 @@
+package vuforia_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// limitedClock advances instantly by the durations waited for, until the limit of waits is reached
+type limitedClock struct {
+	*fakeClock
+	limit int
+}
+
+func (c *limitedClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	n := len(c.waits)
+	c.mu.Unlock()
+	if n >= c.limit {
+		return make(chan time.Time)
+	}
+	return c.fakeClock.After(d)
+}
+
+func TestScheduler(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	ctx := context.Background()
+	start := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	launch := f.addTarget("launch", 0, "success")
+	f.update(launch, func(t *fakeTarget) { t.active = false })
+	ended := f.addTarget("ended", 0, "success")
+	gone := f.addTarget("gone", 0, "success")
+	unscheduled := f.addTarget("unscheduled", 0, "success")
+	f.update(unscheduled, func(t *fakeTarget) { t.active = false })
+
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	schedule, err := vuforia.OpenSchedule(path)
+	require.NoError(t, err)
+	require.Error(t, schedule.Add(vuforia.ActivationWindow{TargetId: launch, Start: start, End: start}))
+	require.NoError(t, schedule.Add(vuforia.ActivationWindow{TargetId: launch, Start: start.Add(time.Hour), End: start.Add(2 * time.Hour)}))
+	require.NoError(t, schedule.Add(vuforia.ActivationWindow{TargetId: launch, Start: start.Add(5 * time.Hour)}))
+	require.NoError(t, schedule.Add(vuforia.ActivationWindow{TargetId: ended, Start: start.Add(-48 * time.Hour), End: start.Add(-24 * time.Hour)}))
+	require.NoError(t, schedule.Add(vuforia.ActivationWindow{TargetId: gone, Start: start}))
+	f.remove(gone)
+
+	t.Run("Reconcile", func(t *testing.T) {
+		clock := &fakeClock{now: start.Add(90 * time.Minute)}
+		scheduler, err := vuforia.NewScheduler(vuforia.SchedulerConfig{Client: client, Schedule: schedule, Clock: clock})
+		require.NoError(t, err)
+
+		// The end of the window of "ended" passed while the scheduler was not running
+		report, err := scheduler.Reconcile(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{launch}, report.Activated)
+		require.Equal(t, []string{ended}, report.Deactivated)
+		require.Equal(t, []string{gone}, report.Removed)
+		require.Empty(t, report.Failed)
+		require.True(t, f.target(launch).active)
+		require.False(t, f.target(ended).active)
+		require.False(t, f.target(unscheduled).active)
+
+		// The removal is persisted
+		reopened, err := vuforia.OpenSchedule(path)
+		require.NoError(t, err)
+		require.Empty(t, reopened.Windows(gone))
+		require.Len(t, reopened.Windows(launch), 2)
+
+		report, err = scheduler.Reconcile(ctx)
+		require.NoError(t, err)
+		require.Empty(t, report.Activated)
+		require.Empty(t, report.Deactivated)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		clock := &limitedClock{fakeClock: &fakeClock{now: start.Add(90 * time.Minute)}, limit: 3}
+		scheduler, err := vuforia.NewScheduler(vuforia.SchedulerConfig{Client: client, Schedule: schedule, Clock: clock, Interval: 4 * time.Hour})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan error)
+		go func() { done <- scheduler.Run(ctx) }()
+
+		require.Eventually(t, func() bool {
+			clock.mu.Lock()
+			defer clock.mu.Unlock()
+			return len(clock.waits) == 3
+		}, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+
+		// Woken up at the end of the window, at the start of the next one, then after the interval
+		require.Equal(t, []time.Duration{30 * time.Minute, 3 * time.Hour, 4 * time.Hour}, clock.waits)
+		require.True(t, f.target(launch).active)
+	})
+}
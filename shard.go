@@ -0,0 +1,306 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Shard is one of the databases a catalog is split across
+type Shard struct {
+	// Name identifies the shard; it is persisted in the ownership file
+	Name   string
+	Client Client
+}
+
+// PlacementStrategy returns the index of the shard to add a new target to
+type PlacementStrategy func(ctx context.Context, shards []Shard, input *PostTargetRequest) (int, error)
+
+// LeastFull places the targets in the shard with the most targets left before its target quota
+func LeastFull() PlacementStrategy {
+	return func(ctx context.Context, shards []Shard, input *PostTargetRequest) (int, error) {
+		best, bestFree := -1, 0
+		for i, s := range shards {
+			summary, err := s.Client.DatabaseSummary(ctx)
+			if err != nil {
+				return 0, fmt.Errorf("shard %s: %w", s.Name, err)
+			}
+			free := summary.TargetQuota - summary.ActiveImages - summary.InactiveImages - summary.FailedImages - summary.ProcessingImages
+			if best == -1 || free > bestFree {
+				best, bestFree = i, free
+			}
+		}
+		if bestFree <= 0 {
+			return 0, APIError{ResultCode: "TargetQuotaReached"}
+		}
+		return best, nil
+	}
+}
+
+// HashName places the targets by the hash of their name, so that a name is always placed in the same shard
+// and names stay unique across the shards
+func HashName() PlacementStrategy {
+	return func(ctx context.Context, shards []Shard, input *PostTargetRequest) (int, error) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(input.Name))
+		return int(h.Sum32() % uint32(len(shards))), nil
+	}
+}
+
+type ShardRouterConfig struct {
+	// Shards are the databases the targets are split across
+	Shards []Shard
+	// Placement chooses the shard of the new targets (Optional)
+	// Defaults to LeastFull.
+	Placement PlacementStrategy
+	// OwnershipPath is a file the shard of every target is persisted to (Optional)
+	// The shard of a target missing from it is found by retrieving the target from every shard.
+	OwnershipPath string
+}
+
+// ShardRouter is a Client splitting the targets of a catalog across several databases.
+// Operations on a target are sent to the shard owning it; listings and database summaries are aggregated.
+type ShardRouter struct {
+	cfg    ShardRouterConfig
+	shards map[string]Shard
+
+	mu sync.Mutex
+	// owners maps the target IDs to the name of their shard
+	owners map[string]string
+}
+
+func NewShardRouter(cfg ShardRouterConfig) (*ShardRouter, error) {
+	if len(cfg.Shards) == 0 {
+		return nil, errors.New("shard router Shards must be set")
+	}
+
+	if cfg.Placement == nil {
+		cfg.Placement = LeastFull()
+	}
+
+	r := &ShardRouter{cfg: cfg, shards: map[string]Shard{}, owners: map[string]string{}}
+	for _, s := range cfg.Shards {
+		if s.Name == "" || s.Client == nil {
+			return nil, errors.New("shard Name and Client must be set")
+		}
+		if _, ok := r.shards[s.Name]; ok {
+			return nil, fmt.Errorf("shard %s is defined twice", s.Name)
+		}
+		r.shards[s.Name] = s
+	}
+
+	if cfg.OwnershipPath != "" {
+		data, err := ioutil.ReadFile(cfg.OwnershipPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &r.owners); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Shard returns the name of the shard owning the target
+func (r *ShardRouter) Shard(ctx context.Context, targetId string) (string, error) {
+	s, err := r.owner(ctx, targetId)
+	if err != nil {
+		return "", err
+	}
+	return s.Name, nil
+}
+
+// ShardSummaries retrieves the summary of every shard, by name
+func (r *ShardRouter) ShardSummaries(ctx context.Context) (map[string]*DatabaseSummaryResponse, error) {
+	summaries := map[string]*DatabaseSummaryResponse{}
+	for _, s := range r.cfg.Shards {
+		summary, err := s.Client.DatabaseSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.Name, err)
+		}
+		summaries[s.Name] = summary
+	}
+	return summaries, nil
+}
+
+func (r *ShardRouter) PostTarget(ctx context.Context, input *PostTargetRequest) (*PostTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	i, err := r.cfg.Placement(ctx, r.cfg.Shards, input)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(r.cfg.Shards) {
+		return nil, fmt.Errorf("placement returned shard %d out of %d", i, len(r.cfg.Shards))
+	}
+
+	s := r.cfg.Shards[i]
+	resp, err := s.Client.PostTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setOwner(resp.TargetId, s.Name); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (r *ShardRouter) GetTarget(ctx context.Context, input *GetTargetRequest) (*GetTargetResponse, error) {
+	s, err := r.owner(ctx, input.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.GetTarget(ctx, input)
+}
+
+func (r *ShardRouter) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (*UpdateTargetResponse, error) {
+	s, err := r.owner(ctx, input.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.UpdateTarget(ctx, input)
+}
+
+func (r *ShardRouter) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (*DeleteTargetResponse, error) {
+	s, err := r.owner(ctx, input.TargetId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Client.DeleteTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setOwner(input.TargetId, ""); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func (r *ShardRouter) TargetSummary(ctx context.Context, input *TargetSummaryRequest) (*TargetSummaryResponse, error) {
+	s, err := r.owner(ctx, input.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.TargetSummary(ctx, input)
+}
+
+// Duplicates retrieves the targets of the shard of the target whose image is similar to the image of the target
+func (r *ShardRouter) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	s, err := r.owner(ctx, input.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	return findDuplicates(ctx, s.Client, input)
+}
+
+// DatabaseSummary returns the sum of the counts, quotas and recognitions of the shards.
+// The name is the names of the shard databases joined by commas, and the RecoThreshold the lowest of the shards.
+func (r *ShardRouter) DatabaseSummary(ctx context.Context) (*DatabaseSummaryResponse, error) {
+	total := &DatabaseSummaryResponse{ResultCode: "Success"}
+	var names []string
+	for i, s := range r.cfg.Shards {
+		summary, err := s.Client.DatabaseSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.Name, err)
+		}
+		names = append(names, summary.Name)
+		total.ActiveImages += summary.ActiveImages
+		total.InactiveImages += summary.InactiveImages
+		total.FailedImages += summary.FailedImages
+		total.ProcessingImages += summary.ProcessingImages
+		total.TargetQuota += summary.TargetQuota
+		total.RequestQuota += summary.RequestQuota
+		total.RequestUsage += summary.RequestUsage
+		if i == 0 || summary.RecoThreshold < total.RecoThreshold {
+			total.RecoThreshold = summary.RecoThreshold
+		}
+		total.TotalRecos += summary.TotalRecos
+		total.CurrentMonthRecos += summary.CurrentMonthRecos
+		total.PreviousMonthRecos += summary.PreviousMonthRecos
+	}
+	total.Name = strings.Join(names, ",")
+	return total, nil
+}
+
+// ListTargets retrieves the IDs of the targets of all the shards, and records their shard.
+// Targets recorded while listing, such as added concurrently, are kept.
+func (r *ShardRouter) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	all := &ListTargetsResponse{ResultCode: "Success", Results: []string{}}
+	owners := map[string]string{}
+	for _, s := range r.cfg.Shards {
+		list, err := listTargets(ctx, s.Client)
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", s.Name, err)
+		}
+		for _, id := range list.Results {
+			owners[id] = s.Name
+		}
+		all.Results = append(all.Results, list.Results...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, shard := range owners {
+		r.owners[id] = shard
+	}
+	return all, r.save()
+}
+
+// owner returns the shard owning the target, retrieving the target from every shard if not known
+func (r *ShardRouter) owner(ctx context.Context, targetId string) (Shard, error) {
+	r.mu.Lock()
+	name, ok := r.owners[targetId]
+	r.mu.Unlock()
+	if s, known := r.shards[name]; ok && known {
+		return s, nil
+	}
+
+	for _, s := range r.cfg.Shards {
+		_, err := s.Client.GetTarget(ctx, &GetTargetRequest{TargetId: targetId})
+		var ae APIError
+		if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+			continue
+		}
+		if err != nil {
+			return Shard{}, fmt.Errorf("shard %s: %w", s.Name, err)
+		}
+		return s, r.setOwner(targetId, s.Name)
+	}
+	return Shard{}, APIError{ResultCode: "UnknownTarget"}
+}
+
+// setOwner records the shard of the target, or forgets the target if the shard is empty
+func (r *ShardRouter) setOwner(targetId, shard string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if shard == "" {
+		delete(r.owners, targetId)
+	} else {
+		r.owners[targetId] = shard
+	}
+	return r.save()
+}
+
+// save writes the owners to the ownership file, if any; the lock must be held
+func (r *ShardRouter) save() error {
+	if r.cfg.OwnershipPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.owners, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.cfg.OwnershipPath, data)
+}
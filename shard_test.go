@@ -0,0 +1,93 @@
+package vuforia_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestShardRouter(t *testing.T) {
+	a, b := newFakeVWS(t), newFakeVWS(t)
+	ctx := context.Background()
+	image := base64.StdEncoding.EncodeToString([]byte("image"))
+	ownership := filepath.Join(t.TempDir(), "owners.json")
+	shards := []vuforia.Shard{
+		{Name: "a", Client: a.client(t, vuforia.ClientConfig{})},
+		{Name: "b", Client: b.client(t, vuforia.ClientConfig{})},
+	}
+
+	_, err := vuforia.NewShardRouter(vuforia.ShardRouterConfig{})
+	require.Error(t, err)
+	_, err = vuforia.NewShardRouter(vuforia.ShardRouterConfig{Shards: []vuforia.Shard{shards[0], shards[0]}})
+	require.Error(t, err)
+
+	a.addTarget("existing-1", 0, "success")
+	a.addTarget("existing-2", 0, "success")
+	b.addTarget("existing-3", 0, "success")
+
+	router, err := vuforia.NewShardRouter(vuforia.ShardRouterConfig{Shards: shards, OwnershipPath: ownership})
+	require.NoError(t, err)
+
+	// The least full shard is b, then both are as full
+	first, err := router.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "first", Width: 1, Image: image})
+	require.NoError(t, err)
+	require.NotNil(t, b.target(first.TargetId))
+	shard, err := router.Shard(ctx, first.TargetId)
+	require.NoError(t, err)
+	require.Equal(t, "b", shard)
+
+	// Target IDs of the fakes collide, so the router must rely on ownership rather than probing
+	output, err := router.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: first.TargetId})
+	require.NoError(t, err)
+	require.Equal(t, "first", output.TargetRecord.Name)
+
+	summary, err := router.DatabaseSummary(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "fake,fake", summary.Name)
+	require.Equal(t, 2000, summary.TargetQuota)
+	require.Equal(t, 1000, summary.RecoThreshold, "the threshold is not added up")
+	require.Equal(t, 4, summary.ActiveImages)
+
+	list, err := router.ListTargets(ctx)
+	require.NoError(t, err)
+	require.Len(t, list.Results, 4)
+
+	t.Run("Ownership", func(t *testing.T) {
+		reopened, err := vuforia.NewShardRouter(vuforia.ShardRouterConfig{Shards: shards, OwnershipPath: ownership})
+		require.NoError(t, err)
+		shard, err := reopened.Shard(ctx, first.TargetId)
+		require.NoError(t, err)
+		require.Equal(t, "b", shard)
+
+		// Unknown targets are looked for in every shard
+		probing, err := vuforia.NewShardRouter(vuforia.ShardRouterConfig{Shards: shards})
+		require.NoError(t, err)
+		_, err = probing.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: first.TargetId})
+		require.NoError(t, err)
+		require.Nil(t, a.target(first.TargetId), "the first shard having the ID owns it")
+		require.NotNil(t, b.target(first.TargetId))
+
+		_, err = probing.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: "missing"})
+		require.Equal(t, "UnknownTarget", err.(vuforia.APIError).ResultCode)
+	})
+
+	t.Run("HashName", func(t *testing.T) {
+		router, err := vuforia.NewShardRouter(vuforia.ShardRouterConfig{Shards: shards, Placement: vuforia.HashName()})
+		require.NoError(t, err)
+
+		placed := map[string]int{}
+		for i := 0; i < 20; i++ {
+			resp, err := router.PostTarget(ctx, &vuforia.PostTargetRequest{Name: fmt.Sprintf("hashed-%d", i), Width: 1, Image: image})
+			require.NoError(t, err)
+			shard, err := router.Shard(ctx, resp.TargetId)
+			require.NoError(t, err)
+			placed[shard]++
+		}
+		require.Len(t, placed, 2, "names are spread across the shards")
+	})
+}
@@ -0,0 +1,86 @@
+package vuforia
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TargetStatus is the processing status of a target
+type TargetStatus string
+
+const (
+	StatusProcessing TargetStatus = "processing"
+	StatusSuccess    TargetStatus = "success"
+	StatusFailed     TargetStatus = "failed"
+)
+
+// Processed reports whether the target is done processing, successfully or not
+func (s TargetStatus) Processed() bool {
+	return s == StatusSuccess || s == StatusFailed
+}
+
+// UnmarshalJSON normalizes the case of the known statuses; other statuses are kept as they are
+func (s *TargetStatus) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*s = TargetStatus(v)
+	for _, known := range []TargetStatus{StatusProcessing, StatusSuccess, StatusFailed} {
+		if strings.EqualFold(v, string(known)) {
+			*s = known
+		}
+	}
+	return nil
+}
+
+// TrackingRating is the rating of a target image for tracking purposes, from 0 to 5
+type TrackingRating int
+
+// TrackingRatingUnrated is the rating of a target image that has not been rated yet
+const TrackingRatingUnrated TrackingRating = -1
+
+// Rated reports whether the image has been rated
+func (r TrackingRating) Rated() bool {
+	return r != TrackingRatingUnrated
+}
+
+// dateLayout is the format of the dates in the VWS API
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, formatted as YYYY-MM-DD in JSON
+type Date struct {
+	time.Time
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	if v == "" {
+		*d = Date{}
+		return nil
+	}
+
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return err
+	}
+	*d = Date{Time: t}
+	return nil
+}
@@ -0,0 +1,56 @@
+package vuforia_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestTargetJSON(t *testing.T) {
+	t.Run("GetTargetResponse", func(t *testing.T) {
+		wire := `{"transaction_id":"tx","result_code":"Success","status":"processing","target_record":{"target_id":"id","active_flag":true,"name":"name","width":2,"tracking_rating":-1}}`
+
+		var v vuforia.GetTargetResponse
+		require.NoError(t, json.Unmarshal([]byte(wire), &v))
+		require.Equal(t, vuforia.StatusProcessing, v.Status)
+		require.False(t, v.Status.Processed())
+		require.Equal(t, vuforia.TrackingRatingUnrated, v.TargetRecord.TrackingRating)
+		require.False(t, v.TargetRecord.TrackingRating.Rated())
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.JSONEq(t, wire, string(data))
+	})
+
+	t.Run("TargetSummaryResponse", func(t *testing.T) {
+		wire := `{"transaction_id":"tx","result_code":"Success","status":"success","database_name":"db","target_name":"name","upload_date":"2021-05-01","active_flag":true,"tracking_rating":5,"total_recos":1,"current_month_recos":1,"previous_month_recos":0}`
+
+		var v vuforia.TargetSummaryResponse
+		require.NoError(t, json.Unmarshal([]byte(wire), &v))
+		require.True(t, v.Status.Processed())
+		require.Equal(t, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), v.UploadDate.Time)
+		require.Equal(t, "2021-05-01", v.UploadDate.String())
+
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.JSONEq(t, wire, string(data))
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		var statuses []vuforia.TargetStatus
+		require.NoError(t, json.Unmarshal([]byte(`["Success","FAILED","archived"]`), &statuses))
+		require.Equal(t, []vuforia.TargetStatus{vuforia.StatusSuccess, vuforia.StatusFailed, "archived"}, statuses)
+	})
+
+	t.Run("Date", func(t *testing.T) {
+		var v struct {
+			Date vuforia.Date `json:"date"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{"date":""}`), &v))
+		require.True(t, v.Date.IsZero())
+		require.Error(t, json.Unmarshal([]byte(`{"date":"01/05/2021"}`), &v))
+	})
+}
@@ -0,0 +1,353 @@
+package vuforia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrTenantQuotaReached is returned when a tenant adds a target beyond its MaxTargets
+var ErrTenantQuotaReached = errors.New("vuforia tenant target quota reached")
+
+// tenantSeparator ends the name prefixes; it cannot appear elsewhere in a prefix so that no prefix starts with another
+const tenantSeparator = "-"
+
+// TenantMetadata is the application metadata of the targets added through a TenantClient
+type TenantMetadata struct {
+	Tenant string `json:"tenant"`
+	// Metadata is the base64 encoded application metadata given by the tenant
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// ParseTenantMetadata decodes the application metadata of a target added through a TenantClient,
+// such as returned by Cloud Recognition queries
+func ParseTenantMetadata(metadata string) (*TenantMetadata, error) {
+	var v TenantMetadata
+	if err := DecodeMetadata(metadata, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+type TenantConfig struct {
+	// TenantId identifies the tenant in the metadata of its targets
+	TenantId string
+	// NamePrefix is added to the names of the targets of the tenant; it must end with, and contain only one, dash (Optional)
+	// Defaults to the TenantId followed by a dash, in which case the TenantId must not contain a dash.
+	NamePrefix string
+	// MaxTargets is the number of targets the tenant may have; unlimited when 0 (Optional)
+	// The targets of the tenant are listed before every addition to enforce it, including the ones added by
+	// other processes, though processes adding targets at the same time may exceed it.
+	MaxTargets int
+}
+
+// TenantClient confines a tenant to its own targets in a database shared with other tenants.
+// The targets of the tenant are the ones whose name starts with the NamePrefix; the prefix is added to,
+// and removed from, the names going through the client. Targets of other tenants are reported as
+// UnknownTarget. The metadata of the targets is wrapped in a TenantMetadata.
+type TenantClient struct {
+	Client
+	cfg TenantConfig
+
+	mu sync.Mutex
+	// owned tells, per target ID, whether the target belongs to the tenant
+	owned   map[string]bool
+	synced  bool
+	pending int
+}
+
+func NewTenantClient(next Client, cfg TenantConfig) (*TenantClient, error) {
+	if cfg.TenantId == "" {
+		return nil, errors.New("tenant TenantId must be set")
+	}
+
+	if cfg.NamePrefix == "" {
+		cfg.NamePrefix = cfg.TenantId + tenantSeparator
+	}
+
+	// Otherwise the prefix of tenant "acme" would select the targets of tenant "acme-corp"
+	if strings.Index(cfg.NamePrefix, tenantSeparator) != len(cfg.NamePrefix)-len(tenantSeparator) {
+		return nil, fmt.Errorf("tenant NamePrefix %q must end with, and contain only one, %q", cfg.NamePrefix, tenantSeparator)
+	}
+
+	if cfg.MaxTargets < 0 {
+		return nil, errors.New("tenant MaxTargets must not be negative")
+	}
+
+	return &TenantClient{Client: next, cfg: cfg, owned: map[string]bool{}}, nil
+}
+
+func (c *TenantClient) PostTarget(ctx context.Context, input *PostTargetRequest) (*PostTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	metadata, err := c.wrapMetadata(input.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.reserve(ctx); err != nil {
+		return nil, err
+	}
+
+	scoped := *input
+	scoped.Name = c.cfg.NamePrefix + input.Name
+	scoped.Metadata = &metadata
+	resp, err := c.Client.PostTarget(ctx, &scoped)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending--
+	if err != nil {
+		return nil, err
+	}
+	c.owned[resp.TargetId] = true
+	return resp, nil
+}
+
+func (c *TenantClient) GetTarget(ctx context.Context, input *GetTargetRequest) (*GetTargetResponse, error) {
+	output, err := c.Client.GetTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := c.classify(input.TargetId, output.TargetRecord.Name)
+	if !ok {
+		return nil, c.unknown()
+	}
+	output.TargetRecord.Name = name
+	return output, nil
+}
+
+func (c *TenantClient) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (*UpdateTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	if err := c.checkOwned(ctx, input.TargetId); err != nil {
+		return nil, err
+	}
+
+	scoped := *input
+	if input.Name != nil {
+		name := c.cfg.NamePrefix + *input.Name
+		scoped.Name = &name
+	}
+	if input.Metadata != nil {
+		metadata, err := c.wrapMetadata(input.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		scoped.Metadata = &metadata
+	}
+	return c.Client.UpdateTarget(ctx, &scoped)
+}
+
+func (c *TenantClient) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (*DeleteTargetResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+
+	if err := c.checkOwned(ctx, input.TargetId); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Client.DeleteTarget(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.owned, input.TargetId)
+	return resp, nil
+}
+
+func (c *TenantClient) TargetSummary(ctx context.Context, input *TargetSummaryRequest) (*TargetSummaryResponse, error) {
+	output, err := c.Client.TargetSummary(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := c.classify(input.TargetId, output.TargetName)
+	if !ok {
+		return nil, c.unknown()
+	}
+	output.TargetName = name
+	return output, nil
+}
+
+// ListTargets retrieves the IDs of the targets of the tenant
+func (c *TenantClient) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	list, err := listTargets(ctx, c.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	owned := []string{}
+	for _, id := range list.Results {
+		ok, err := c.owns(ctx, id)
+		var ae APIError
+		if errors.As(err, &ae) && ae.ResultCode == "UnknownTarget" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			owned = append(owned, id)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Forget the targets deleted through other clients
+	listed := map[string]bool{}
+	for _, id := range list.Results {
+		listed[id] = true
+	}
+	for id := range c.owned {
+		if !listed[id] {
+			delete(c.owned, id)
+		}
+	}
+	c.synced = true
+
+	list.Results = owned
+	return list, nil
+}
+
+// Duplicates retrieves the targets of the tenant whose image is similar to the image of the target
+func (c *TenantClient) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	if err := c.checkOwned(ctx, input.TargetId); err != nil {
+		return nil, err
+	}
+
+	output, err := findDuplicates(ctx, c.Client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	similar := []string{}
+	for _, id := range output.SimilarTargets {
+		if ok, err := c.owns(ctx, id); err == nil && ok {
+			similar = append(similar, id)
+		}
+	}
+	output.SimilarTargets = similar
+	return output, nil
+}
+
+// Count returns the number of targets of the tenant, listing the targets of the database on the first call
+func (c *TenantClient) Count(ctx context.Context) (int, error) {
+	if err := c.sync(ctx); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.countLocked(), nil
+}
+
+// countLocked returns the number of targets of the tenant; the lock must be held
+func (c *TenantClient) countLocked() int {
+	n := 0
+	for _, owned := range c.owned {
+		if owned {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *TenantClient) sync(ctx context.Context) error {
+	c.mu.Lock()
+	synced := c.synced
+	c.mu.Unlock()
+	if synced {
+		return nil
+	}
+	_, err := c.ListTargets(ctx)
+	return err
+}
+
+// reserve counts a target being added against the quota
+func (c *TenantClient) reserve(ctx context.Context) error {
+	if c.cfg.MaxTargets == 0 {
+		c.mu.Lock()
+		c.pending++
+		c.mu.Unlock()
+		return nil
+	}
+
+	// Count the targets added by other processes of the tenant as well
+	if _, err := c.ListTargets(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.countLocked()+c.pending >= c.cfg.MaxTargets {
+		return ErrTenantQuotaReached
+	}
+	c.pending++
+	return nil
+}
+
+// checkOwned returns UnknownTarget if the target does not belong to the tenant
+func (c *TenantClient) checkOwned(ctx context.Context, targetId string) error {
+	ok, err := c.owns(ctx, targetId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return c.unknown()
+	}
+	return nil
+}
+
+// owns reports whether the target belongs to the tenant, retrieving it if not known yet
+func (c *TenantClient) owns(ctx context.Context, targetId string) (bool, error) {
+	c.mu.Lock()
+	owned, known := c.owned[targetId]
+	c.mu.Unlock()
+	if known {
+		return owned, nil
+	}
+
+	output, err := c.Client.GetTarget(ctx, &GetTargetRequest{TargetId: targetId})
+	if err != nil {
+		return false, err
+	}
+	_, owned = c.classify(targetId, output.TargetRecord.Name)
+	return owned, nil
+}
+
+// classify records whether the target belongs to the tenant by its name, and returns the name without the prefix
+func (c *TenantClient) classify(targetId, name string) (string, bool) {
+	owned := strings.HasPrefix(name, c.cfg.NamePrefix)
+	c.mu.Lock()
+	c.owned[targetId] = owned
+	c.mu.Unlock()
+	return strings.TrimPrefix(name, c.cfg.NamePrefix), owned
+}
+
+func (c *TenantClient) wrapMetadata(metadata *string) (string, error) {
+	v := TenantMetadata{Tenant: c.cfg.TenantId}
+	if metadata != nil {
+		v.Metadata = *metadata
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return EncodeRawMetadata(data, MetadataOptions{})
+}
+
+func (c *TenantClient) unknown() error {
+	return APIError{ResultCode: "UnknownTarget"}
+}
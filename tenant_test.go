@@ -0,0 +1,121 @@
+package vuforia_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestTenantClient(t *testing.T) {
+	f := newFakeVWS(t)
+	ctx := context.Background()
+	image := base64.StdEncoding.EncodeToString([]byte("image"))
+
+	_, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{})
+	require.Error(t, err)
+
+	preexisting := f.addTarget("acme-legacy", 0, "success")
+	acme, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "acme", MaxTargets: 2})
+	require.NoError(t, err)
+	globex, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "globex"})
+	require.NoError(t, err)
+
+	// Both tenants may use the same name
+	metadata := base64.StdEncoding.EncodeToString([]byte("acme data"))
+	poster, err := acme.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "poster", Width: 1, Image: image, Metadata: &metadata})
+	require.NoError(t, err)
+	other, err := globex.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "poster", Width: 1, Image: image})
+	require.NoError(t, err)
+	require.Equal(t, "acme-poster", f.target(poster.TargetId).name)
+	require.Equal(t, "globex-poster", f.target(other.TargetId).name)
+
+	tagged, err := vuforia.ParseTenantMetadata(f.target(poster.TargetId).metadata)
+	require.NoError(t, err)
+	require.Equal(t, vuforia.TenantMetadata{Tenant: "acme", Metadata: metadata}, *tagged)
+	tagged, err = vuforia.ParseTenantMetadata(f.target(other.TargetId).metadata)
+	require.NoError(t, err)
+	require.Equal(t, vuforia.TenantMetadata{Tenant: "globex"}, *tagged)
+
+	output, err := acme.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: poster.TargetId})
+	require.NoError(t, err)
+	require.Equal(t, "poster", output.TargetRecord.Name)
+
+	list, err := acme.ListTargets(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{preexisting, poster.TargetId}, list.Results)
+	count, err := acme.Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	isUnknown := func(err error) bool {
+		var ae vuforia.APIError
+		return errors.As(err, &ae) && ae.ResultCode == "UnknownTarget"
+	}
+
+	t.Run("Isolation", func(t *testing.T) {
+		_, err := acme.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: other.TargetId})
+		require.True(t, isUnknown(err))
+		_, err = acme.TargetSummary(ctx, &vuforia.TargetSummaryRequest{TargetId: other.TargetId})
+		require.True(t, isUnknown(err))
+		active := false
+		_, err = acme.UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: other.TargetId, Active: &active})
+		require.True(t, isUnknown(err))
+		_, err = acme.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: other.TargetId})
+		require.True(t, isUnknown(err))
+		require.True(t, f.target(other.TargetId).active)
+
+		duplicates, err := globex.Duplicates(ctx, &vuforia.DuplicatesRequest{TargetId: other.TargetId})
+		require.NoError(t, err)
+		require.Empty(t, duplicates.SimilarTargets, "the duplicate belongs to another tenant")
+	})
+
+	t.Run("PrefixOfAnotherTenant", func(t *testing.T) {
+		_, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "acme-corp"})
+		require.Error(t, err, "the prefix of acme would select the targets of acme-corp")
+		_, err = vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "acme-corp", NamePrefix: "acme-corp-"})
+		require.Error(t, err)
+
+		acmeCorp, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "acme-corp", NamePrefix: "acmecorp-"})
+		require.NoError(t, err)
+		banner, err := acmeCorp.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "banner", Width: 1, Image: image})
+		require.NoError(t, err)
+		_, err = acme.GetTarget(ctx, &vuforia.GetTargetRequest{TargetId: banner.TargetId})
+		require.True(t, isUnknown(err))
+		f.remove(banner.TargetId)
+	})
+
+	t.Run("Quota", func(t *testing.T) {
+		_, err := acme.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "flyer", Width: 1, Image: image})
+		require.ErrorIs(t, err, vuforia.ErrTenantQuotaReached)
+
+		f.update(poster.TargetId, func(t *fakeTarget) { t.status = "success" })
+		name := "renamed"
+		_, err = acme.UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: poster.TargetId, Name: &name})
+		require.NoError(t, err)
+		require.Equal(t, "acme-renamed", f.target(poster.TargetId).name)
+
+		_, err = acme.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: poster.TargetId})
+		require.NoError(t, err)
+		_, err = acme.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "flyer", Width: 1, Image: image})
+		require.NoError(t, err)
+	})
+
+	t.Run("QuotaAcrossClients", func(t *testing.T) {
+		f := newFakeVWS(t)
+		first, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "initech", MaxTargets: 2})
+		require.NoError(t, err)
+		second, err := vuforia.NewTenantClient(f.client(t, vuforia.ClientConfig{}), vuforia.TenantConfig{TenantId: "initech", MaxTargets: 2})
+		require.NoError(t, err)
+
+		_, err = first.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "a", Width: 1, Image: image})
+		require.NoError(t, err)
+		_, err = second.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "b", Width: 1, Image: image})
+		require.NoError(t, err)
+		_, err = first.PostTarget(ctx, &vuforia.PostTargetRequest{Name: "c", Width: 1, Image: image})
+		require.ErrorIs(t, err, vuforia.ErrTenantQuotaReached, "the target added by the other client is counted")
+	})
+}
@@ -0,0 +1,60 @@
+package vuforia
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the tracer used by this package
+const tracerName = "github.com/yznima/vuforia-client-go"
+
+// Attributes recorded on the spans of every operation
+const (
+	attrTargetId      = attribute.Key("vuforia.target_id")
+	attrResultCode    = attribute.Key("vuforia.result_code")
+	attrTransactionId = attribute.Key("vuforia.transaction_id")
+	attrAttempt       = attribute.Key("vuforia.attempt")
+	attrHTTPMethod    = attribute.Key("http.request.method")
+	attrHTTPStatus    = attribute.Key("http.response.status_code")
+)
+
+// result holds the fields common to every VWS API response
+type result struct {
+	TargetId      string `json:"target_id"`
+	TransactionId string `json:"transaction_id"`
+	ResultCode    string `json:"result_code"`
+}
+
+func setResultAttributes(span trace.Span, res result) {
+	if res.TargetId != "" {
+		span.SetAttributes(attrTargetId.String(res.TargetId))
+	}
+	if res.ResultCode != "" {
+		span.SetAttributes(attrResultCode.String(res.ResultCode))
+	}
+	if res.TransactionId != "" {
+		span.SetAttributes(attrTransactionId.String(res.TransactionId))
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracerFor returns the tracer of the client if it has one, otherwise the global tracer
+func tracerFor(c Client) trace.Tracer {
+	if t, ok := c.(interface{ tracerOf() trace.Tracer }); ok {
+		return t.tracerOf()
+	}
+	return otel.Tracer(tracerName)
+}
+
+func (c *client) tracerOf() trace.Tracer {
+	return c.tracer
+}
@@ -0,0 +1,109 @@
+package vuforia_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	newTracedClient := func(t *testing.T, f *fakeVWS, retries int) (vuforia.Client, *tracetest.InMemoryExporter) {
+		exporter := tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		return f.client(t, vuforia.ClientConfig{TracerProvider: tp, MaxRetries: retries}), exporter
+	}
+
+	t.Run("Operation", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client, exporter := newTracedClient(t, f, 0)
+
+		resp, err := client.PostTarget(context.Background(), &vuforia.PostTargetRequest{Name: "target", Width: 1})
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		attempt, op := spans[0], spans[1]
+		require.Equal(t, "PostTarget", op.Name)
+		require.Equal(t, trace.SpanKindClient, op.SpanKind)
+		require.Contains(t, op.Attributes, attribute.String("vuforia.target_id", resp.TargetId))
+		require.Contains(t, op.Attributes, attribute.String("vuforia.result_code", "TargetCreated"))
+		require.Contains(t, op.Attributes, attribute.String("vuforia.transaction_id", resp.TransactionId))
+
+		require.Equal(t, "POST", attempt.Name)
+		require.Equal(t, op.SpanContext.SpanID(), attempt.Parent.SpanID())
+		require.Contains(t, attempt.Attributes, attribute.Int("vuforia.attempt", 1))
+		require.Contains(t, attempt.Attributes, attribute.Int("http.response.status_code", http.StatusCreated))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client, exporter := newTracedClient(t, f, 0)
+
+		_, err := client.GetTarget(context.Background(), &vuforia.GetTargetRequest{TargetId: "unknown"})
+		require.Error(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		op := spans[1]
+		require.Equal(t, "GetTarget", op.Name)
+		require.Equal(t, codes.Error, op.Status.Code)
+		require.Contains(t, op.Attributes, attribute.String("vuforia.target_id", "unknown"))
+		require.Contains(t, op.Attributes, attribute.String("vuforia.result_code", "UnknownTarget"))
+		require.Len(t, op.Events, 1)
+		require.Equal(t, "exception", op.Events[0].Name)
+		require.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusNotFound))
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client, exporter := newTracedClient(t, f, 1)
+		f.fail(http.StatusServiceUnavailable, "")
+
+		_, err := client.DatabaseSummary(context.Background())
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 3)
+		first, second, op := spans[0], spans[1], spans[2]
+		require.Equal(t, "DatabaseSummary", op.Name)
+		require.Equal(t, codes.Error, first.Status.Code)
+		require.Contains(t, first.Attributes, attribute.Int("vuforia.attempt", 1))
+		require.Contains(t, first.Attributes, attribute.Int("http.response.status_code", http.StatusServiceUnavailable))
+		require.Contains(t, second.Attributes, attribute.Int("vuforia.attempt", 2))
+		require.Contains(t, second.Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+		require.Equal(t, codes.Unset, op.Status.Code)
+
+		// VWS may have created the target before failing
+		exporter.Reset()
+		f.fail(http.StatusServiceUnavailable, "")
+		_, err = client.PostTarget(context.Background(), &vuforia.PostTargetRequest{Name: "target", Width: 1})
+		require.Error(t, err)
+		require.Len(t, exporter.GetSpans(), 2)
+		require.Equal(t, 1, f.requestCount("/targets"))
+	})
+
+	t.Run("WaitUntilProcessed", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client, exporter := newTracedClient(t, f, 0)
+		id := f.addTarget("target", 0, "success")
+
+		require.NoError(t, vuforia.WaitUntilProcessed(context.Background(), client, id))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 3)
+		get, wait := spans[1], spans[2]
+		require.Equal(t, "WaitUntilProcessed", wait.Name)
+		require.Contains(t, wait.Attributes, attribute.String("vuforia.target_id", id))
+		require.Contains(t, wait.Attributes, attribute.Int("vuforia.polls", 1))
+		require.Equal(t, "GetTarget", get.Name)
+		require.Equal(t, wait.SpanContext.SpanID(), get.Parent.SpanID())
+	})
+}
@@ -6,13 +6,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // vuforiaUrl is the endpoint for the Vuforia Web Services API
 const vuforiaUrl = "vws.vuforia.com"
 
+// retryBackoff is the wait before the first retry of a request; it doubles for every subsequent retry
+const retryBackoff = 500 * time.Millisecond
+
 type Client interface {
 	// PostTarget adds a new target
 	PostTarget(context.Context, *PostTargetRequest) (*PostTargetResponse, error)
@@ -28,13 +36,45 @@ type Client interface {
 	DatabaseSummary(context.Context) (*DatabaseSummaryResponse, error)
 }
 
+// Lister is implemented by the clients able to list the targets of the database, such as the one returned by NewClient
+type Lister interface {
+	// ListTargets retrieves the IDs of all the targets in the database
+	ListTargets(context.Context) (*ListTargetsResponse, error)
+}
+
+// DuplicateFinder is implemented by the clients able to find similar targets, such as the one returned by NewClient
+type DuplicateFinder interface {
+	// Duplicates retrieves the targets whose image is similar to the image of the target
+	Duplicates(context.Context, *DuplicatesRequest) (*DuplicatesResponse, error)
+}
+
 type ClientConfig struct {
 	SecretKey, AccessKey string
 	Client               *http.Client
+	// TracerProvider is used to create a span per operation and per attempt (Optional)
+	// The global OpenTelemetry TracerProvider is used when not set.
+	TracerProvider trace.TracerProvider
+	// MaxRetries is the number of times a request is retried after a server error (Optional)
+	// PostTarget is never retried since VWS may have created the target before failing.
+	MaxRetries int
+	// Metrics collects metrics about the requests sent by the client (Optional)
+	Metrics *Metrics
+	// RateLimiter limits the rate of requests sent by the client (Optional)
+	RateLimiter RateLimiter
+	// WaitProcessing makes UpdateTarget and DeleteTarget wait for the target to be processed, then retry,
+	// when they fail with TargetStatusProcessing; the wait is bounded by the context (Optional)
+	// Operations are not retried when not set.
+	WaitProcessing *WaitOptions
+}
+
+// RateLimiter blocks until a request may be sent; *rate.Limiter from golang.org/x/time/rate satisfies it
+type RateLimiter interface {
+	Wait(context.Context) error
 }
 
 type client struct {
-	cfg ClientConfig
+	cfg    ClientConfig
+	tracer trace.Tracer
 }
 
 func NewClient(cfg ClientConfig) (Client, error) {
@@ -50,7 +90,15 @@ func NewClient(cfg ClientConfig) (Client, error) {
 		cfg.Client = http.DefaultClient
 	}
 
-	return &client{cfg: cfg}, nil
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if cfg.MaxRetries < 0 {
+		return nil, fmt.Errorf("vuforia MaxRetries must not be negative")
+	}
+
+	return &client{cfg: cfg, tracer: cfg.TracerProvider.Tracer(tracerName)}, nil
 }
 
 type PostTargetRequest struct {
@@ -86,28 +134,8 @@ func (c *client) PostTarget(ctx context.Context, input *PostTargetRequest) (*Pos
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://%s/targets", vuforiaUrl), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, body); err != nil {
-		return nil, err
-	}
-
-	resp, err := c.cfg.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer safeClose(resp)
-
-	if err := checkError(resp); err != nil {
-		return nil, err
-	}
-
 	var v PostTargetResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
-	if err != nil {
+	if err := c.do(ctx, "PostTarget", "", http.MethodPost, "/targets", body, &v); err != nil {
 		return nil, err
 	}
 
@@ -126,20 +154,22 @@ type GetTargetResponse struct {
 	// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Interperete-VWS-API-Result-Codes
 	ResultCode string `json:"result_code"`
 	// Status of the target (Processing, Success, Failure)
-	Status string `json:"status"`
+	Status TargetStatus `json:"status"`
 	// TargetRecord is the target information
-	TargetRecord struct {
-		// TargetId is the ID of the target
-		TargetId string `json:"target_id"`
-		// Active indicates whether or not the target is active for query; default is true
-		Active bool `json:"active_flag"`
-		// Name of the target, unique within a database
-		Name string `json:"name"`
-		// Width of the target in scene unit
-		Width float64 `json:"width"`
-		// TrackingRating is the rating of the target recognition image for tracking purposes
-		TrackingRating int `json:"tracking_rating"`
-	} `json:"target_record"`
+	TargetRecord TargetRecord `json:"target_record"`
+}
+
+type TargetRecord struct {
+	// TargetId is the ID of the target
+	TargetId string `json:"target_id"`
+	// Active indicates whether or not the target is active for query; default is true
+	Active bool `json:"active_flag"`
+	// Name of the target, unique within a database
+	Name string `json:"name"`
+	// Width of the target in scene unit
+	Width float64 `json:"width"`
+	// TrackingRating is the rating of the target recognition image for tracking purposes
+	TrackingRating TrackingRating `json:"tracking_rating"`
 }
 
 // https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Retrieve-a-Target-Record
@@ -151,28 +181,8 @@ func (c *client) GetTarget(ctx context.Context, input *GetTargetRequest) (*GetTa
 		return nil, errors.New("TargetId must be provided")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/targets/%s", vuforiaUrl, input.TargetId), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, nil); err != nil {
-		return nil, err
-	}
-
-	resp, err := c.cfg.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer safeClose(resp)
-
-	if err := checkError(resp); err != nil {
-		return nil, err
-	}
-
 	var v GetTargetResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
-	if err != nil {
+	if err := c.do(ctx, "GetTarget", input.TargetId, http.MethodGet, "/targets/"+input.TargetId, nil, &v); err != nil {
 		return nil, err
 	}
 
@@ -216,28 +226,10 @@ func (c *client) UpdateTarget(ctx context.Context, input *UpdateTargetRequest) (
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("https://%s/targets/%s", vuforiaUrl, input.TargetId), bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, body); err != nil {
-		return nil, err
-	}
-
-	resp, err := c.cfg.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer safeClose(resp)
-
-	if err := checkError(resp); err != nil {
-		return nil, err
-	}
-
 	var v UpdateTargetResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
-	if err != nil {
+	if err := c.retryProcessing(ctx, input.TargetId, func() error {
+		return c.do(ctx, "UpdateTarget", input.TargetId, http.MethodPut, "/targets/"+input.TargetId, body, &v)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -266,28 +258,10 @@ func (c *client) DeleteTarget(ctx context.Context, input *DeleteTargetRequest) (
 		return nil, errors.New("TargetId must be provided")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("https://%s/targets/%s", vuforiaUrl, input.TargetId), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, nil); err != nil {
-		return nil, err
-	}
-
-	resp, err := c.cfg.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer safeClose(resp)
-
-	if err := checkError(resp); err != nil {
-		return nil, err
-	}
-
 	var v DeleteTargetResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
-	if err != nil {
+	if err := c.retryProcessing(ctx, input.TargetId, func() error {
+		return c.do(ctx, "DeleteTarget", input.TargetId, http.MethodDelete, "/targets/"+input.TargetId, nil, &v)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -306,17 +280,17 @@ type TargetSummaryResponse struct {
 	// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Interperete-VWS-API-Result-Codes
 	ResultCode string `json:"result_code"`
 	// Status of the target (Processing, Success, Failure)
-	Status string `json:"status"`
+	Status TargetStatus `json:"status"`
 	// DatabaseName of is the database name the current target resides in
 	DatabaseName string `json:"database_name"`
 	// TargteName is the target name
 	TargetName string `json:"target_name"`
 	// UploadDate is the date of target upload (Specified as YYYY-MM-DD)
-	UploadDate string `json:"upload_date"`
+	UploadDate Date `json:"upload_date"`
 	// Active indicates whether or not the target is active for query; default is true
 	Active bool `json:"active_flag"`
 	// TrackingRating is the rating of the target recognition image for tracking purposes
-	TrackingRating int `json:"tracking_rating"`
+	TrackingRating TrackingRating `json:"tracking_rating"`
 	// TotalRecos is the total count of the recognitions for this target
 	TotalRecos int `json:"total_recos"`
 	// CurrentMonthRecos is the total count of recognitions in the current month (Set to 0 if the Status is not "Success")
@@ -334,28 +308,8 @@ func (c *client) TargetSummary(ctx context.Context, input *TargetSummaryRequest)
 		return nil, errors.New("TargetId must be provided")
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/summary/%s", vuforiaUrl, input.TargetId), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, nil); err != nil {
-		return nil, err
-	}
-
-	resp, err := c.cfg.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer safeClose(resp)
-
-	if err := checkError(resp); err != nil {
-		return nil, err
-	}
-
 	var v TargetSummaryResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
-	if err != nil {
+	if err := c.do(ctx, "TargetSummary", input.TargetId, http.MethodGet, "/summary/"+input.TargetId, nil, &v); err != nil {
 		return nil, err
 	}
 
@@ -376,36 +330,217 @@ type DatabaseSummaryResponse struct {
 	InactiveImages int `json:"inactive_images"`
 	// FailedImages is the total number of images with status = fail for the data
 	FailedImages int `json:"failed_images"`
+	// ProcessingImages is the total number of images with status = processing for the database
+	ProcessingImages int `json:"processing_images"`
+	// TargetQuota is the maximum number of targets allowed in the database
+	TargetQuota int `json:"target_quota"`
+	// RequestQuota is the maximum number of API requests allowed for the database in the current period
+	RequestQuota int `json:"request_quota"`
+	// RequestUsage is the number of API requests made for the database in the current period
+	RequestUsage int `json:"request_usage"`
+	// RecoThreshold is the maximum number of recognitions allowed for the database
+	RecoThreshold int `json:"reco_threshold"`
+	// TotalRecos is the total count of the recognitions for the database
+	TotalRecos int `json:"total_recos"`
+	// CurrentMonthRecos is the total count of the recognitions in the current month for the database
+	CurrentMonthRecos int `json:"current_month_recos"`
+	// PreviousMonthRecos is the total count of the recognitions in the previous month for the database
+	PreviousMonthRecos int `json:"previous_month_recos"`
 }
 
 // https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Get-a-Database-Summary-Report
 func (c *client) DatabaseSummary(ctx context.Context) (*DatabaseSummaryResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s/summary", vuforiaUrl), nil)
-	if err != nil {
+	var v DatabaseSummaryResponse
+	if err := c.do(ctx, "DatabaseSummary", "", http.MethodGet, "/summary", nil, &v); err != nil {
 		return nil, err
 	}
+	c.cfg.Metrics.ObserveDatabaseSummary(&v)
+
+	return &v, nil
+}
+
+type ListTargetsResponse struct {
+	// TransactionId is the ID of the transaction
+	TransactionId string `json:"transaction_id"`
+	// ResultCode is one of the VWS API Result Code.
+	// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Interperete-VWS-API-Result-Codes
+	ResultCode string `json:"result_code"`
+	// Results are the IDs of the targets in the database
+	Results []string `json:"results"`
+}
+
+// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Get-a-Target-List-for-a-Cloud-Database
+func (c *client) ListTargets(ctx context.Context) (*ListTargetsResponse, error) {
+	var v ListTargetsResponse
+	if err := c.do(ctx, "ListTargets", "", http.MethodGet, "/targets", nil, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// listTargets lists the targets of the database through the client, which must implement Lister
+func listTargets(ctx context.Context, client Client) (*ListTargetsResponse, error) {
+	l, ok := client.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("vuforia client %T does not implement Lister", client)
+	}
+	return l.ListTargets(ctx)
+}
+
+type DuplicatesRequest struct {
+	// TargetId is the ID of the target to check for duplicates of
+	TargetId string
+}
+
+type DuplicatesResponse struct {
+	// TransactionId is the ID of the transaction
+	TransactionId string `json:"transaction_id"`
+	// ResultCode is one of the VWS API Result Code.
+	// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Interperete-VWS-API-Result-Codes
+	ResultCode string `json:"result_code"`
+	// SimilarTargets are the IDs of the targets whose image is similar, excluding the target itself
+	SimilarTargets []string `json:"similar_targets"`
+}
+
+// https://library.vuforia.com/articles/Solution/How-To-Use-the-Vuforia-Web-Services-API.html#How-To-Check-for-Duplicate-Targets
+func (c *client) Duplicates(ctx context.Context, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	if input == nil {
+		panic("input is <nil>")
+	}
+	if input.TargetId == "" {
+		return nil, errors.New("TargetId must be provided")
+	}
 
-	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, nil); err != nil {
+	var v DuplicatesResponse
+	if err := c.do(ctx, "Duplicates", input.TargetId, http.MethodGet, "/duplicates/"+input.TargetId, nil, &v); err != nil {
 		return nil, err
 	}
 
+	return &v, nil
+}
+
+// findDuplicates retrieves the duplicates of the target through the client, which must implement DuplicateFinder
+func findDuplicates(ctx context.Context, client Client, input *DuplicatesRequest) (*DuplicatesResponse, error) {
+	f, ok := client.(DuplicateFinder)
+	if !ok {
+		return nil, fmt.Errorf("vuforia client %T does not implement DuplicateFinder", client)
+	}
+	return f.Duplicates(ctx, input)
+}
+
+// do sends a signed request to the Vuforia Web Services API and decodes the response into v.
+// Requests failing with a server error are retried up to MaxRetries times, except POST requests which are not idempotent.
+func (c *client) do(ctx context.Context, op, targetId, method, path string, body []byte, v interface{}) (err error) {
+	ctx, span := c.tracer.Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient))
+	if targetId != "" {
+		span.SetAttributes(attrTargetId.String(targetId))
+	}
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	start := time.Now()
+	var res result
+	defer func() {
+		c.cfg.Metrics.observeOperation(op, time.Since(start), res, err)
+	}()
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		if c.cfg.RateLimiter != nil {
+			waitStart := time.Now()
+			if err := c.cfg.RateLimiter.Wait(ctx); err != nil {
+				return err
+			}
+			c.cfg.Metrics.observeLimiterWait(op, time.Since(waitStart))
+		}
+
+		c.cfg.Metrics.observeAttempt(op, attempt)
+		res, err = c.attempt(ctx, attempt, method, path, body, v)
+		setResultAttributes(span, res)
+		if err == nil || attempt > c.cfg.MaxRetries || method == http.MethodPost || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// retryProcessing runs the operation again once the target is processed, as long as it fails with
+// TargetStatusProcessing and WaitProcessing is set
+func (c *client) retryProcessing(ctx context.Context, targetId string, op func() error) error {
+	for {
+		err := op()
+		var ae APIError
+		if c.cfg.WaitProcessing == nil || !errors.As(err, &ae) || ae.ResultCode != "TargetStatusProcessing" {
+			return err
+		}
+
+		// A target failing processing can be updated or deleted
+		if _, err := WaitUntilProcessedWithOptions(ctx, c, targetId, *c.cfg.WaitProcessing); err != nil && !errors.Is(err, ErrProcessingFailed) {
+			return err
+		}
+	}
+}
+
+// attempt performs a single HTTP round trip of an operation
+func (c *client) attempt(ctx context.Context, attempt int, method, path string, body []byte, v interface{}) (res result, err error) {
+	ctx, span := c.tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrAttempt.Int(attempt), attrHTTPMethod.String(method)),
+	)
+	defer func() {
+		setResultAttributes(span, res)
+		endSpan(span, err)
+	}()
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("https://%s%s", vuforiaUrl, path), reqBody)
+	if err != nil {
+		return res, err
+	}
+
+	if err = prepare(c.cfg.SecretKey, c.cfg.AccessKey, req, body); err != nil {
+		return res, err
+	}
+
 	resp, err := c.cfg.Client.Do(req)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 	defer safeClose(resp)
 
+	span.SetAttributes(attrHTTPStatus.Int(resp.StatusCode))
+
 	if err := checkError(resp); err != nil {
-		return nil, err
+		var ae APIError
+		if errors.As(err, &ae) {
+			res = result{ResultCode: ae.ResultCode, TransactionId: ae.TransactionId}
+		}
+		return res, err
 	}
 
-	var v DatabaseSummaryResponse
-	err = json.NewDecoder(resp.Body).Decode(&v)
+	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 
-	return &v, nil
+	// The result is decoded separately so that it can be reported regardless of the operation
+	if err := json.Unmarshal(data, &res); err != nil {
+		return res, err
+	}
+
+	return res, json.Unmarshal(data, v)
 }
 
 func safeClose(resp *http.Response) {
@@ -35,7 +35,8 @@ func TestTargetCRUD(t *testing.T) {
 			name := newTargetName()
 			width := float64(2)
 			active := true
-			metadata := base64.RawStdEncoding.EncodeToString([]byte("License: Free to use under the Unsplash License"))
+			metadata, err := vuforia.EncodeRawMetadata([]byte("License: Free to use under the Unsplash License"), vuforia.MetadataOptions{})
+			require.NoError(t, err)
 			resp, err := client.PostTarget(context.Background(), &vuforia.PostTargetRequest{
 				Name:     name,
 				Width:    width,
@@ -74,7 +75,8 @@ func TestTargetCRUD(t *testing.T) {
 			name2 := newTargetName()
 			width2 := float64(3)
 			image2 := base64.RawStdEncoding.EncodeToString(artWork)
-			metadata2 := base64.RawStdEncoding.EncodeToString([]byte("New License: Free to use under the Unsplash License"))
+			metadata2, err := vuforia.EncodeRawMetadata([]byte("New License: Free to use under the Unsplash License"), vuforia.MetadataOptions{})
+			require.NoError(t, err)
 			updateResp, err := client.UpdateTarget(context.Background(), &vuforia.UpdateTargetRequest{
 				TargetId: targetId,
 				Name:     &name2,
@@ -117,11 +119,11 @@ func TestTargetCRUD(t *testing.T) {
 			require.NotEmpty(t, summaryResp.TargetName)
 			require.NotEmpty(t, summaryResp.DatabaseName)
 			require.NotEmpty(t, summaryResp.UploadDate)
-			require.Equal(t, summaryResp.TrackingRating, 5)
+			require.Equal(t, summaryResp.TrackingRating, vuforia.TrackingRating(5))
 			require.Equal(t, summaryResp.TotalRecos, 0)
 			require.Equal(t, summaryResp.CurrentMonthRecos, 0)
 			require.Equal(t, summaryResp.PreviousMonthRecos, 0)
-			require.Equal(t, "success", summaryResp.Status)
+			require.Equal(t, vuforia.StatusSuccess, summaryResp.Status)
 		})
 
 		t.Run("Delete & Get", func(t *testing.T) {
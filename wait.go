@@ -3,40 +3,231 @@ package vuforia
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrProcessingFailed is returned when the target is processed but failed
+var ErrProcessingFailed = errors.New("vuforia target processing failed")
+
+// ErrWaitTimeout is returned when the target is still processing after the maximum wait
+var ErrWaitTimeout = errors.New("vuforia target still processing after the maximum wait")
+
+// UnexpectedStatusError is returned when the target is in a status other than processing, success or failed
+type UnexpectedStatusError struct {
+	TargetId string
+	Status   TargetStatus
+}
+
+func (e UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("vuforia target %s is in unexpected status %q", e.TargetId, e.Status)
+}
+
+type WaitOptions struct {
+	// InitialInterval is the time before the first poll and between the first two polls (Optional)
+	// Defaults to 5 seconds.
+	InitialInterval time.Duration
+	// Multiplier increases the interval after every poll for exponential backoff (Optional)
+	// Defaults to 1, i.e. a constant interval.
+	Multiplier float64
+	// MaxInterval is the maximum interval between two polls (Optional)
+	MaxInterval time.Duration
+	// QuotaInterval is the interval after a poll failing with RequestQuotaReached (Optional)
+	// Defaults to 30 seconds.
+	QuotaInterval time.Duration
+	// MaxWait is the maximum time to wait before returning ErrWaitTimeout (Optional)
+	MaxWait time.Duration
+	// Progress is called with the target retrieved by every poll (Optional)
+	Progress func(*GetTargetResponse)
+	// Clock is used to wait between polls (Optional)
+	// Defaults to the system clock.
+	Clock Clock
+}
+
+// WaitUntilProcessed waits until the target is processed, successfully or not.
+// It polls the target every 5 seconds.
 func WaitUntilProcessed(ctx context.Context, client Client, target string) error {
-	defaultInterval := 5 * time.Second
-	extendedInterval := 30 * time.Second
-	interval := defaultInterval
+	_, err := WaitUntilProcessedWithOptions(ctx, client, target, WaitOptions{})
+	if errors.Is(err, ErrProcessingFailed) {
+		return nil
+	}
+	return err
+}
+
+// WaitUntilProcessedWithOptions waits until the target is processed and returns it as last retrieved.
+// It returns ErrProcessingFailed along with the target if the processing failed.
+// Errors retrieving the target, including UnknownTarget, are returned as they are except for
+// RequestQuotaReached, after which polling continues at the QuotaInterval.
+func WaitUntilProcessedWithOptions(ctx context.Context, client Client, target string, opts WaitOptions) (output *GetTargetResponse, err error) {
+	ctx, span := tracerFor(client).Start(ctx, "WaitUntilProcessed")
+	span.SetAttributes(attrTargetId.String(target))
+	polls := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("vuforia.polls", polls))
+		if output != nil {
+			span.SetAttributes(attribute.String("vuforia.status", string(output.Status)))
+		}
+		endSpan(span, err)
+	}()
+
+	if opts.InitialInterval == 0 {
+		opts.InitialInterval = 5 * time.Second
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = 1
+	}
+	if opts.QuotaInterval == 0 {
+		opts.QuotaInterval = 30 * time.Second
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+
+	var deadline time.Time
+	if opts.MaxWait > 0 {
+		deadline = opts.Clock.Now().Add(opts.MaxWait)
+	}
+
+	interval := opts.InitialInterval
+	wait := interval
 	for {
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(opts.Clock.Now())
+			if remaining <= 0 {
+				return output, ErrWaitTimeout
+			}
+			if wait > remaining {
+				wait = remaining
+			}
+		}
+
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(interval):
-			interval = defaultInterval
-
-			output, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: target})
-			if err != nil {
-				var ae APIError
-				if errors.As(err, &ae) && strings.EqualFold(ae.ResultCode, "RequestQuotaReached") {
-					interval = extendedInterval
-					continue
-				}
-
-				return err
-			}
+			return output, ctx.Err()
+		case <-opts.Clock.After(wait):
+		}
 
-			switch strings.ToLower(output.Status) {
-			case "failed", "success":
-				return nil
-			case "processing":
-				continue
-			default:
+		polls++
+		current, err := client.GetTarget(ctx, &GetTargetRequest{TargetId: target})
+		if err != nil {
+			var ae APIError
+			if errors.As(err, &ae) && strings.EqualFold(ae.ResultCode, "RequestQuotaReached") {
+				wait = opts.QuotaInterval
 				continue
 			}
+
+			return output, err
+		}
+
+		output = current
+		if opts.Progress != nil {
+			opts.Progress(output)
+		}
+
+		switch output.Status {
+		case StatusSuccess:
+			return output, nil
+		case StatusFailed:
+			return output, ErrProcessingFailed
+		case StatusProcessing:
+			interval = time.Duration(float64(interval) * opts.Multiplier)
+			if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+				interval = opts.MaxInterval
+			}
+			wait = interval
+		default:
+			return output, UnexpectedStatusError{TargetId: target, Status: output.Status}
 		}
 	}
 }
+
+// WaitResult is the outcome of waiting for a target to be processed
+type WaitResult struct {
+	TargetId string
+	// Target is the target as last retrieved; nil if it was never retrieved
+	Target *GetTargetResponse
+	// Err is nil if the target was processed successfully, ErrProcessingFailed if the processing failed,
+	// or the error retrieving the target
+	Err error
+}
+
+type WaitAllOptions struct {
+	// RequestInterval is the minimum time between two requests across all the targets (Optional)
+	// Defaults to 1 second.
+	RequestInterval time.Duration
+	// PollInterval is the minimum time between two requests for the same target (Optional)
+	// Defaults to 5 seconds.
+	PollInterval time.Duration
+	// OnResult is called with the outcome of every target as soon as it is known (Optional)
+	OnResult func(WaitResult)
+}
+
+// WaitUntilAllProcessed waits until all the targets are processed, polling them in turn so that
+// the requests across all the targets are sent at most every RequestInterval.
+// The outcomes are returned in the order they became known. If the context is done first,
+// the outcomes known so far are returned along with the context error.
+func WaitUntilAllProcessed(ctx context.Context, client Client, targetIds []string, opts WaitAllOptions) ([]WaitResult, error) {
+	remaining := map[string]bool{}
+	for _, id := range targetIds {
+		remaining[id] = true
+	}
+	if len(remaining) == 0 {
+		return nil, nil
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var results []WaitResult
+	var watcher *Watcher
+	complete := func(res WaitResult) {
+		if !remaining[res.TargetId] {
+			return
+		}
+		delete(remaining, res.TargetId)
+		watcher.Remove(res.TargetId)
+		results = append(results, res)
+		if opts.OnResult != nil {
+			opts.OnResult(res)
+		}
+		if len(remaining) == 0 {
+			cancel()
+		}
+	}
+
+	watcher, err := NewWatcher(WatcherConfig{
+		Client:             client,
+		RequestInterval:    opts.RequestInterval,
+		ProcessingInterval: opts.PollInterval,
+		OnEvent: func(e Event) {
+			switch e.Type {
+			case EventSucceeded:
+				complete(WaitResult{TargetId: e.TargetId, Target: e.Target})
+			case EventFailed:
+				complete(WaitResult{TargetId: e.TargetId, Target: e.Target, Err: ErrProcessingFailed})
+			case EventDeleted:
+				complete(WaitResult{TargetId: e.TargetId, Target: e.Previous, Err: APIError{ResultCode: "UnknownTarget"}})
+			}
+		},
+		OnError: func(targetId string, err error) {
+			var ae APIError
+			if errors.As(err, &ae) && strings.EqualFold(ae.ResultCode, "RequestQuotaReached") {
+				return
+			}
+			complete(WaitResult{TargetId: targetId, Err: err})
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	watcher.Add(targetIds...)
+
+	_ = watcher.Run(runCtx)
+	if len(remaining) > 0 {
+		return results, ctx.Err()
+	}
+	return results, nil
+}
@@ -0,0 +1,187 @@
+package vuforia_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// fakeClock advances instantly by the durations waited for
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestWaitUntilProcessedWithOptions(t *testing.T) {
+	t.Run("Backoff", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := f.client(t, vuforia.ClientConfig{})
+		id := f.addTarget("target", 5, "success")
+		f.fail(http.StatusTooManyRequests, "RequestQuotaReached")
+
+		clock := &fakeClock{}
+		var progress []vuforia.TargetStatus
+		output, err := vuforia.WaitUntilProcessedWithOptions(context.Background(), client, id, vuforia.WaitOptions{
+			InitialInterval: time.Second,
+			Multiplier:      2,
+			MaxInterval:     5 * time.Second,
+			QuotaInterval:   time.Minute,
+			Clock:           clock,
+			Progress: func(output *vuforia.GetTargetResponse) {
+				progress = append(progress, output.Status)
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, vuforia.StatusSuccess, output.Status)
+		require.Equal(t, []vuforia.TargetStatus{
+			vuforia.StatusProcessing, vuforia.StatusProcessing, vuforia.StatusProcessing, vuforia.StatusProcessing, vuforia.StatusSuccess,
+		}, progress)
+		require.Equal(t, []time.Duration{
+			time.Second, time.Minute, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+		}, clock.waits)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := f.client(t, vuforia.ClientConfig{})
+		id := f.addTarget("target", 1, "failed")
+
+		output, err := vuforia.WaitUntilProcessedWithOptions(context.Background(), client, id, vuforia.WaitOptions{Clock: &fakeClock{}})
+		require.ErrorIs(t, err, vuforia.ErrProcessingFailed)
+		require.Equal(t, vuforia.StatusFailed, output.Status)
+	})
+
+	t.Run("MaxWait", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := f.client(t, vuforia.ClientConfig{})
+		id := f.addTarget("target", 100, "success")
+
+		clock := &fakeClock{}
+		output, err := vuforia.WaitUntilProcessedWithOptions(context.Background(), client, id, vuforia.WaitOptions{
+			InitialInterval: 4 * time.Second,
+			MaxWait:         10 * time.Second,
+			Clock:           clock,
+		})
+		require.ErrorIs(t, err, vuforia.ErrWaitTimeout)
+		require.Equal(t, vuforia.StatusProcessing, output.Status)
+		require.Equal(t, []time.Duration{4 * time.Second, 4 * time.Second, 2 * time.Second}, clock.waits)
+	})
+
+	t.Run("UnknownTarget", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := f.client(t, vuforia.ClientConfig{})
+
+		output, err := vuforia.WaitUntilProcessedWithOptions(context.Background(), client, "unknown", vuforia.WaitOptions{Clock: &fakeClock{}})
+		var ae vuforia.APIError
+		require.ErrorAs(t, err, &ae)
+		require.Equal(t, "UnknownTarget", ae.ResultCode)
+		require.Nil(t, output)
+	})
+
+	t.Run("UnexpectedStatus", func(t *testing.T) {
+		f := newFakeVWS(t)
+		client := f.client(t, vuforia.ClientConfig{})
+		id := f.addTarget("target", 0, "archived")
+
+		_, err := vuforia.WaitUntilProcessedWithOptions(context.Background(), client, id, vuforia.WaitOptions{Clock: &fakeClock{}})
+		require.Equal(t, vuforia.UnexpectedStatusError{TargetId: id, Status: "archived"}, err)
+	})
+}
+
+func TestWaitUntilAllProcessed(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	slow := f.addTarget("slow", 4, "success")
+	failing := f.addTarget("failing", 2, "failed")
+	stuck := f.addTarget("stuck", 1000, "success")
+	opts := vuforia.WaitAllOptions{RequestInterval: time.Millisecond, PollInterval: time.Millisecond}
+
+	t.Run("All", func(t *testing.T) {
+		var streamed []string
+		opts := opts
+		opts.OnResult = func(res vuforia.WaitResult) {
+			streamed = append(streamed, res.TargetId)
+		}
+
+		results, err := vuforia.WaitUntilAllProcessed(context.Background(), client, []string{slow, failing, "unknown"}, opts)
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Equal(t, []string{results[0].TargetId, results[1].TargetId, results[2].TargetId}, streamed)
+
+		byId := map[string]vuforia.WaitResult{}
+		for _, res := range results {
+			byId[res.TargetId] = res
+		}
+		require.NoError(t, byId[slow].Err)
+		require.Equal(t, vuforia.StatusSuccess, byId[slow].Target.Status)
+		require.ErrorIs(t, byId[failing].Err, vuforia.ErrProcessingFailed)
+		require.Error(t, byId["unknown"].Err)
+		require.Nil(t, byId["unknown"].Target)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		before := f.requestCount("/targets/" + slow)
+		results, err := vuforia.WaitUntilAllProcessed(ctx, client, []string{slow, stuck}, opts)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Len(t, results, 1)
+		require.Equal(t, slow, results[0].TargetId)
+		require.Equal(t, before+1, f.requestCount("/targets/"+slow), "completed targets are no longer polled")
+	})
+}
+
+func TestClientWaitProcessing(t *testing.T) {
+	f := newFakeVWS(t)
+	ctx := context.Background()
+	name := "renamed"
+
+	// Without the option, the error is returned as is
+	id := f.addTarget("target", 2, "success")
+	_, err := f.client(t, vuforia.ClientConfig{}).UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: id, Name: &name})
+	require.Equal(t, "TargetStatusProcessing", err.(vuforia.APIError).ResultCode)
+
+	client := f.client(t, vuforia.ClientConfig{WaitProcessing: &vuforia.WaitOptions{InitialInterval: time.Millisecond}})
+	_, err = client.UpdateTarget(ctx, &vuforia.UpdateTargetRequest{TargetId: id, Name: &name})
+	require.NoError(t, err)
+	require.Equal(t, "renamed", f.target(id).name)
+	require.Equal(t, 5, f.requestCount("/targets/"+id), "PUT, PUT, GET, GET, PUT")
+
+	failed := f.addTarget("failed", 1, "failed")
+	_, err = client.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: failed})
+	require.NoError(t, err)
+	require.Nil(t, f.target(failed))
+
+	t.Run("Deadline", func(t *testing.T) {
+		id := f.addTarget("slow", 1000, "success")
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := client.DeleteTarget(ctx, &vuforia.DeleteTargetRequest{TargetId: id})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotNil(t, f.target(id))
+	})
+}
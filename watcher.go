@@ -0,0 +1,253 @@
+package vuforia
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventProcessing is emitted when the target starts processing
+	EventProcessing EventType = "Processing"
+	// EventSucceeded is emitted when the target is processed successfully
+	EventSucceeded EventType = "Succeeded"
+	// EventFailed is emitted when the target fails processing
+	EventFailed EventType = "Failed"
+	// EventDeleted is emitted when the target no longer exists; the target is no longer watched
+	EventDeleted EventType = "Deleted"
+	// EventActiveFlagChanged is emitted when the target is activated or deactivated
+	EventActiveFlagChanged EventType = "ActiveFlagChanged"
+	// EventRatingChanged is emitted when the tracking rating of the target changes
+	EventRatingChanged EventType = "RatingChanged"
+)
+
+// Event describes a change of state of a watched target
+type Event struct {
+	Type     EventType
+	TargetId string
+	// Target is the target as last retrieved; nil for EventDeleted
+	Target *GetTargetResponse
+	// Previous is the target as retrieved before the change; nil when first retrieved
+	Previous *GetTargetResponse
+	// Time the change was observed
+	Time time.Time
+}
+
+type WatcherConfig struct {
+	// Client is used to retrieve the targets
+	Client Client
+	// RequestInterval is the minimum time between two requests across all the targets (Optional)
+	// Defaults to 1 second.
+	RequestInterval time.Duration
+	// ProcessingInterval is the minimum time between two requests for a target that is processing (Optional)
+	// Defaults to 5 seconds.
+	ProcessingInterval time.Duration
+	// SettledInterval is the minimum time between two requests for a target that is processed (Optional)
+	// Defaults to 1 minute.
+	SettledInterval time.Duration
+	// OnEvent is called with every event, instead of sending it on the Events channel (Optional)
+	OnEvent func(Event)
+	// OnError is called when retrieving a target fails (Optional)
+	OnError func(targetId string, err error)
+	// EventBuffer is the capacity of the Events channel (Optional)
+	// Defaults to 64.
+	EventBuffer int
+}
+
+// Watcher polls a set of targets and emits an Event whenever one of them changes state
+type Watcher struct {
+	cfg    WatcherConfig
+	events chan Event
+
+	mu          sync.Mutex
+	targets     map[string]*watchedTarget
+	pausedUntil time.Time
+}
+
+type watchedTarget struct {
+	last     *GetTargetResponse
+	nextPoll time.Time
+}
+
+func NewWatcher(cfg WatcherConfig) (*Watcher, error) {
+	if cfg.Client == nil {
+		return nil, errors.New("watcher Client must be set")
+	}
+
+	if cfg.RequestInterval == 0 {
+		cfg.RequestInterval = time.Second
+	}
+
+	if cfg.ProcessingInterval == 0 {
+		cfg.ProcessingInterval = 5 * time.Second
+	}
+
+	if cfg.SettledInterval == 0 {
+		cfg.SettledInterval = time.Minute
+	}
+
+	if cfg.EventBuffer == 0 {
+		cfg.EventBuffer = 64
+	}
+
+	return &Watcher{
+		cfg:     cfg,
+		events:  make(chan Event, cfg.EventBuffer),
+		targets: map[string]*watchedTarget{},
+	}, nil
+}
+
+// Events returns the channel the events are sent on when OnEvent is not set.
+// The channel is closed when Run returns.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Add starts watching the targets; they are polled as soon as possible
+func (w *Watcher) Add(targetIds ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range targetIds {
+		if _, ok := w.targets[id]; !ok {
+			w.targets[id] = &watchedTarget{}
+		}
+	}
+}
+
+// Remove stops watching the targets
+func (w *Watcher) Remove(targetIds ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range targetIds {
+		delete(w.targets, id)
+	}
+}
+
+// Len returns the number of watched targets
+func (w *Watcher) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.targets)
+}
+
+// Run polls the targets until the context is done. It must be called only once.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.cfg.RequestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if err := w.poll(ctx, now); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// poll retrieves the target that is due the earliest, if any
+func (w *Watcher) poll(ctx context.Context, now time.Time) error {
+	w.mu.Lock()
+	if now.Before(w.pausedUntil) {
+		w.mu.Unlock()
+		return nil
+	}
+
+	var id string
+	var due *watchedTarget
+	for tid, t := range w.targets {
+		if !t.nextPoll.After(now) && (due == nil || t.nextPoll.Before(due.nextPoll)) {
+			id, due = tid, t
+		}
+	}
+	w.mu.Unlock()
+
+	if due == nil {
+		return nil
+	}
+
+	output, err := w.cfg.Client.GetTarget(ctx, &GetTargetRequest{TargetId: id})
+
+	w.mu.Lock()
+	if w.targets[id] != due {
+		// The target was removed while being retrieved
+		w.mu.Unlock()
+		return nil
+	}
+
+	var events []Event
+	var ae APIError
+	switch {
+	case err == nil:
+		events = changes(id, due.last, output, now)
+		due.last = output
+		due.nextPoll = now.Add(w.cfg.SettledInterval)
+		if output.Status == StatusProcessing {
+			due.nextPoll = now.Add(w.cfg.ProcessingInterval)
+		}
+	case errors.As(err, &ae) && ae.ResultCode == "UnknownTarget":
+		events = []Event{{Type: EventDeleted, TargetId: id, Previous: due.last, Time: now}}
+		delete(w.targets, id)
+	case errors.As(err, &ae) && ae.ResultCode == "RequestQuotaReached":
+		w.pausedUntil = now.Add(30 * time.Second)
+	default:
+		due.nextPoll = now.Add(w.cfg.ProcessingInterval)
+	}
+	w.mu.Unlock()
+
+	if err != nil && w.cfg.OnError != nil && ctx.Err() == nil {
+		var ae APIError
+		if !errors.As(err, &ae) || ae.ResultCode != "UnknownTarget" {
+			w.cfg.OnError(id, err)
+		}
+	}
+
+	for _, e := range events {
+		if w.cfg.OnEvent != nil {
+			w.cfg.OnEvent(e)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case w.events <- e:
+		}
+	}
+	return nil
+}
+
+// changes returns the events describing the differences between two retrievals of a target
+func changes(id string, prev, cur *GetTargetResponse, now time.Time) []Event {
+	event := func(t EventType) Event {
+		return Event{Type: t, TargetId: id, Target: cur, Previous: prev, Time: now}
+	}
+
+	var events []Event
+	if prev == nil || prev.Status != cur.Status {
+		switch cur.Status {
+		case StatusProcessing:
+			events = append(events, event(EventProcessing))
+		case StatusSuccess:
+			events = append(events, event(EventSucceeded))
+		case StatusFailed:
+			events = append(events, event(EventFailed))
+		}
+	}
+
+	if prev != nil && prev.TargetRecord.Active != cur.TargetRecord.Active {
+		events = append(events, event(EventActiveFlagChanged))
+	}
+
+	if prev != nil && prev.TargetRecord.TrackingRating != cur.TargetRecord.TrackingRating {
+		events = append(events, event(EventRatingChanged))
+	}
+
+	return events
+}
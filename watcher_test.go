@@ -0,0 +1,77 @@
+package vuforia_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+func TestWatcher(t *testing.T) {
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	succeeding := f.addTarget("succeeding", 2, "success")
+	failing := f.addTarget("failing", 0, "failed")
+
+	watcher, err := vuforia.NewWatcher(vuforia.WatcherConfig{
+		Client:             client,
+		RequestInterval:    time.Millisecond,
+		ProcessingInterval: time.Millisecond,
+		SettledInterval:    5 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	watcher.Add(succeeding, failing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	next := func() vuforia.Event {
+		select {
+		case e := <-watcher.Events():
+			return e
+		case <-ctx.Done():
+			require.FailNow(t, "no event received")
+			return vuforia.Event{}
+		}
+	}
+
+	seen := map[string][]vuforia.EventType{}
+	for len(seen[succeeding]) < 3 || len(seen[failing]) < 1 {
+		e := next()
+		seen[e.TargetId] = append(seen[e.TargetId], e.Type)
+	}
+	require.Equal(t, []vuforia.EventType{vuforia.EventProcessing, vuforia.EventSucceeded, vuforia.EventRatingChanged}, seen[succeeding])
+	require.Equal(t, []vuforia.EventType{vuforia.EventFailed}, seen[failing])
+
+	watcher.Remove(failing)
+	f.update(succeeding, func(t *fakeTarget) { t.active = false })
+	e := next()
+	require.Equal(t, vuforia.EventActiveFlagChanged, e.Type)
+	require.Equal(t, succeeding, e.TargetId)
+	require.True(t, e.Previous.TargetRecord.Active)
+	require.False(t, e.Target.TargetRecord.Active)
+
+	f.remove(succeeding)
+	e = next()
+	require.Equal(t, vuforia.EventDeleted, e.Type)
+	require.Equal(t, succeeding, e.TargetId)
+	require.Nil(t, e.Target)
+	require.Equal(t, 0, watcher.Len())
+
+	added := f.addTarget("added", 0, "success")
+	watcher.Add(added)
+	e = next()
+	require.Equal(t, vuforia.EventSucceeded, e.Type)
+	require.Equal(t, added, e.TargetId)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	_, ok := <-watcher.Events()
+	require.False(t, ok)
+}
@@ -0,0 +1,421 @@
+package vuforia
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of the webhook requests
+const (
+	WebhookSignatureHeader = "X-Vuforia-Signature"
+	WebhookEventHeader     = "X-Vuforia-Event"
+	WebhookDeliveryHeader  = "X-Vuforia-Delivery"
+)
+
+// WebhookPayload is the JSON body posted to the webhooks
+type WebhookPayload struct {
+	Event          EventType      `json:"event"`
+	TargetId       string         `json:"target_id"`
+	Name           string         `json:"name"`
+	Status         TargetStatus   `json:"status"`
+	Active         bool           `json:"active_flag"`
+	TrackingRating TrackingRating `json:"tracking_rating"`
+	Time           time.Time      `json:"time"`
+}
+
+// Delivery is a payload to post to a webhook
+type Delivery struct {
+	Id      string          `json:"id"`
+	URL     string          `json:"url"`
+	Event   EventType       `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+	// Attempts is the number of failed attempts so far
+	Attempts int `json:"attempts"`
+	// NextAttempt is the earliest time of the next attempt
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// Outbox stores the deliveries until they succeed or are abandoned
+type Outbox interface {
+	Put(Delivery) error
+	Delete(id string) error
+	List() ([]Delivery, error)
+}
+
+type WebhookConfig struct {
+	// Watcher configures how the targets are watched; its OnEvent is set by the notifier
+	Watcher WatcherConfig
+	// URLs are the webhooks notified of every event
+	URLs []string
+	// Secret is the key of the HMAC-SHA256 signature of the payloads
+	Secret []byte
+	// Outbox stores the pending deliveries (Optional)
+	// Defaults to an in memory outbox; use a FileOutbox to keep the deliveries across restarts.
+	Outbox Outbox
+	// HTTPClient is used to post the payloads (Optional)
+	HTTPClient *http.Client
+	// MaxAttempts is the number of attempts before a delivery is abandoned (Optional)
+	// Defaults to 5.
+	MaxAttempts int
+	// RetryInterval is the wait after the first failed attempt; it doubles after every attempt (Optional)
+	// Defaults to 10 seconds.
+	RetryInterval time.Duration
+	// OnAbandon is called when a delivery is abandoned, or with only the Id of a delivery that cannot be read
+	// from the outbox (Optional)
+	OnAbandon func(Delivery, error)
+}
+
+// WebhookNotifier watches targets and posts a signed payload to webhooks when they succeed or fail processing.
+// Targets are no longer watched once processed.
+type WebhookNotifier struct {
+	cfg     WebhookConfig
+	watcher *Watcher
+	kick    chan struct{}
+}
+
+func NewWebhookNotifier(cfg WebhookConfig) (*WebhookNotifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, errors.New("webhook URLs must be set")
+	}
+
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("webhook Secret must be set")
+	}
+
+	if cfg.Outbox == nil {
+		cfg.Outbox = NewMemoryOutbox()
+	}
+
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 5
+	}
+
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = 10 * time.Second
+	}
+
+	n := &WebhookNotifier{cfg: cfg, kick: make(chan struct{}, 1)}
+	cfg.Watcher.OnEvent = n.onEvent
+	watcher, err := NewWatcher(cfg.Watcher)
+	if err != nil {
+		return nil, err
+	}
+	n.watcher = watcher
+
+	return n, nil
+}
+
+// Watch starts watching the targets
+func (n *WebhookNotifier) Watch(targetIds ...string) {
+	n.watcher.Add(targetIds...)
+}
+
+// Unwatch stops watching the targets
+func (n *WebhookNotifier) Unwatch(targetIds ...string) {
+	n.watcher.Remove(targetIds...)
+}
+
+// Run watches the targets and delivers the payloads until the context is done
+func (n *WebhookNotifier) Run(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = n.watcher.Run(ctx)
+	}()
+
+	tick := time.Second
+	if n.cfg.RetryInterval < tick {
+		tick = n.cfg.RetryInterval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		n.deliver(ctx)
+
+		select {
+		case <-ctx.Done():
+			<-done
+			return ctx.Err()
+		case <-ticker.C:
+		case <-n.kick:
+		}
+	}
+}
+
+func (n *WebhookNotifier) onEvent(e Event) {
+	if e.Type != EventSucceeded && e.Type != EventFailed {
+		return
+	}
+	n.watcher.Remove(e.TargetId)
+
+	payload, err := json.Marshal(WebhookPayload{
+		Event:          e.Type,
+		TargetId:       e.TargetId,
+		Name:           e.Target.TargetRecord.Name,
+		Status:         e.Target.Status,
+		Active:         e.Target.TargetRecord.Active,
+		TrackingRating: e.Target.TargetRecord.TrackingRating,
+		Time:           e.Time.UTC(),
+	})
+	if err != nil {
+		return
+	}
+
+	for _, u := range n.cfg.URLs {
+		d := Delivery{Id: newDeliveryId(), URL: u, Event: e.Type, Payload: payload, NextAttempt: e.Time}
+		if err := n.cfg.Outbox.Put(d); err != nil && n.cfg.OnAbandon != nil {
+			n.cfg.OnAbandon(d, err)
+		}
+	}
+
+	select {
+	case n.kick <- struct{}{}:
+	default:
+	}
+}
+
+// deliver attempts the deliveries that are due
+func (n *WebhookNotifier) deliver(ctx context.Context) {
+	deliveries, err := n.cfg.Outbox.List()
+	var corrupt CorruptDeliveriesError
+	if errors.As(err, &corrupt) {
+		// The readable deliveries are still attempted
+		for id, err := range corrupt.Errs {
+			if n.cfg.OnAbandon != nil {
+				n.cfg.OnAbandon(Delivery{Id: id}, err)
+			}
+		}
+	} else if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, d := range deliveries {
+		if d.NextAttempt.After(now) || ctx.Err() != nil {
+			continue
+		}
+
+		err := n.post(ctx, d)
+		if err == nil {
+			_ = n.cfg.Outbox.Delete(d.Id)
+			continue
+		}
+		if ctx.Err() != nil {
+			// Attempted again on the next run
+			return
+		}
+
+		d.Attempts++
+		if d.Attempts >= n.cfg.MaxAttempts {
+			_ = n.cfg.Outbox.Delete(d.Id)
+			if n.cfg.OnAbandon != nil {
+				n.cfg.OnAbandon(d, err)
+			}
+			continue
+		}
+
+		d.NextAttempt = now.Add(n.cfg.RetryInterval << (d.Attempts - 1))
+		_ = n.cfg.Outbox.Put(d)
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, d Delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(d.Event))
+	req.Header.Set(WebhookDeliveryHeader, d.Id)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(n.cfg.Secret, d.Payload))
+
+	resp, err := n.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer safeClose(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", d.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the value of the signature header for the payload
+func SignWebhookPayload(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether the signature header matches the payload
+func VerifyWebhookSignature(secret, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, payload)), []byte(signature))
+}
+
+func newDeliveryId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MemoryOutbox is an Outbox losing its deliveries when the process exits
+type MemoryOutbox struct {
+	mu         sync.Mutex
+	deliveries map[string]Delivery
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{deliveries: map[string]Delivery{}}
+}
+
+func (o *MemoryOutbox) Put(d Delivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.deliveries[d.Id] = d
+	return nil
+}
+
+func (o *MemoryOutbox) Delete(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.deliveries, id)
+	return nil
+}
+
+func (o *MemoryOutbox) List() ([]Delivery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	deliveries := make([]Delivery, 0, len(o.deliveries))
+	for _, d := range o.deliveries {
+		deliveries = append(deliveries, d)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// CorruptDeliveriesError is returned by FileOutbox.List, along with the deliveries it could read, when delivery
+// files cannot be read or decoded. The files are renamed with a .corrupt suffix so that they are no longer listed.
+type CorruptDeliveriesError struct {
+	// Errs are the errors by delivery ID
+	Errs map[string]error
+}
+
+func (e CorruptDeliveriesError) Error() string {
+	ids := make([]string, 0, len(e.Errs))
+	for id := range e.Errs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return fmt.Sprintf("outbox deliveries %s cannot be read", strings.Join(ids, ", "))
+}
+
+// FileOutbox is an Outbox storing every delivery as a JSON file in a directory
+type FileOutbox struct {
+	dir string
+}
+
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileOutbox{dir: dir}, nil
+}
+
+func (o *FileOutbox) Put(d Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(o.path(d.Id), data)
+}
+
+func (o *FileOutbox) Delete(id string) error {
+	err := os.Remove(o.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (o *FileOutbox) List() ([]Delivery, error) {
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []Delivery
+	corrupt := CorruptDeliveriesError{Errs: map[string]error{}}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(o.dir, f.Name())
+		data, err := ioutil.ReadFile(path)
+		var d Delivery
+		if err == nil {
+			err = json.Unmarshal(data, &d)
+		}
+		if err != nil {
+			// Set aside so that a single file does not stall the deliveries
+			_ = os.Rename(path, path+".corrupt")
+			corrupt.Errs[strings.TrimSuffix(f.Name(), ".json")] = err
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	sortDeliveries(deliveries)
+
+	if len(corrupt.Errs) > 0 {
+		return deliveries, corrupt
+	}
+	return deliveries, nil
+}
+
+func (o *FileOutbox) path(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
+
+func sortDeliveries(deliveries []Delivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttempt.Before(deliveries[j].NextAttempt)
+	})
+}
+
+// writeFileAtomic replaces the file with the data so that readers never see a partial write
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
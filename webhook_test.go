@@ -0,0 +1,160 @@
+package vuforia_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yznima/vuforia-client-go"
+)
+
+// webhookReceiver records the payloads posted to it, failing the first requests
+type webhookReceiver struct {
+	mu       sync.Mutex
+	server   *httptest.Server
+	failures int
+	payloads []vuforia.WebhookPayload
+	received chan struct{}
+}
+
+func newWebhookReceiver(t *testing.T, secret []byte, failures int) *webhookReceiver {
+	r := &webhookReceiver{failures: failures, received: make(chan struct{}, 16)}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.True(t, vuforia.VerifyWebhookSignature(secret, body, req.Header.Get(vuforia.WebhookSignatureHeader)))
+		require.NotEmpty(t, req.Header.Get(vuforia.WebhookDeliveryHeader))
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var p vuforia.WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &p))
+		require.Equal(t, string(p.Event), req.Header.Get(vuforia.WebhookEventHeader))
+		r.payloads = append(r.payloads, p)
+		r.received <- struct{}{}
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *webhookReceiver) wait(t *testing.T, n int) []vuforia.WebhookPayload {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "webhook not called")
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]vuforia.WebhookPayload(nil), r.payloads...)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	secret := []byte("secret")
+	f := newFakeVWS(t)
+	client := f.client(t, vuforia.ClientConfig{})
+	succeeding := f.addTarget("succeeding", 2, "success")
+	failing := f.addTarget("failing", 0, "failed")
+
+	flaky := newWebhookReceiver(t, secret, 1)
+	reliable := newWebhookReceiver(t, secret, 0)
+	outbox, err := vuforia.NewFileOutbox(t.TempDir())
+	require.NoError(t, err)
+
+	notifier, err := vuforia.NewWebhookNotifier(vuforia.WebhookConfig{
+		Watcher: vuforia.WatcherConfig{
+			Client:             client,
+			RequestInterval:    time.Millisecond,
+			ProcessingInterval: time.Millisecond,
+		},
+		URLs:          []string{flaky.server.URL, reliable.server.URL},
+		Secret:        secret,
+		Outbox:        outbox,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	notifier.Watch(succeeding, failing)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- notifier.Run(ctx)
+	}()
+
+	for _, r := range []*webhookReceiver{flaky, reliable} {
+		payloads := r.wait(t, 2)
+		events := map[string]vuforia.EventType{}
+		for _, p := range payloads {
+			events[p.TargetId] = p.Event
+		}
+		require.Equal(t, map[string]vuforia.EventType{succeeding: vuforia.EventSucceeded, failing: vuforia.EventFailed}, events)
+	}
+
+	require.Eventually(t, func() bool {
+		pending, err := outbox.List()
+		return err == nil && len(pending) == 0
+	}, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestFileOutbox(t *testing.T) {
+	secret := []byte("secret")
+	dir := t.TempDir()
+	receiver := newWebhookReceiver(t, secret, 0)
+
+	// A delivery left over by a previous process
+	outbox, err := vuforia.NewFileOutbox(dir)
+	require.NoError(t, err)
+	payload, err := json.Marshal(vuforia.WebhookPayload{Event: vuforia.EventSucceeded, TargetId: "target"})
+	require.NoError(t, err)
+	require.NoError(t, outbox.Put(vuforia.Delivery{Id: "leftover", URL: receiver.server.URL, Event: vuforia.EventSucceeded, Payload: payload}))
+	// A delivery file truncated by a crash
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"id":"bro`), 0o600))
+
+	f := newFakeVWS(t)
+	outbox, err = vuforia.NewFileOutbox(dir)
+	require.NoError(t, err)
+	abandoned := make(chan vuforia.Delivery, 1)
+	notifier, err := vuforia.NewWebhookNotifier(vuforia.WebhookConfig{
+		Watcher:   vuforia.WatcherConfig{Client: f.client(t, vuforia.ClientConfig{})},
+		URLs:      []string{receiver.server.URL},
+		Secret:    secret,
+		Outbox:    outbox,
+		OnAbandon: func(d vuforia.Delivery, err error) { abandoned <- d },
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = notifier.Run(ctx)
+	}()
+
+	payloads := receiver.wait(t, 1)
+	require.Equal(t, "target", payloads[0].TargetId)
+	require.Equal(t, "broken", (<-abandoned).Id)
+
+	_, err = os.Stat(filepath.Join(dir, "broken.json.corrupt"))
+	require.NoError(t, err, "the corrupt file is set aside")
+	require.Eventually(t, func() bool {
+		pending, err := outbox.List()
+		return err == nil && len(pending) == 0
+	}, 5*time.Second, time.Millisecond)
+}